@@ -3,14 +3,23 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"compression_tool.nobletk/internal/huff"
 	"compression_tool.nobletk/internal/readwrite"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "train" {
+		runTrain(os.Args[2:])
+		os.Exit(0)
+	}
+
 	f := flags{}
 	pf := f.parseFlags()
 
@@ -20,20 +29,80 @@ func main() {
 		os.Exit(1)
 	}
 
-	if pf.compFlag {
-		data, err := readwrite.ReadFile(*&pf.inputFlag)
+	outputPath := filepath.Join(filepath.Dir(pf.inputFlag), pf.outputFlag)
+
+	dictOpts, err := dictionaryOptions(pf.dictFlag)
+	if err != nil {
+		panic(err)
+	}
+
+	if pf.compFlag && pf.gzipFlag {
+		err := readwrite.StreamFile(pf.inputFlag, outputPath, func(dst io.Writer, src io.Reader) error {
+			zw := huff.NewGzipWriter(dst,
+				huff.WithBlockSize(pf.blockSizeFlag),
+				huff.WithConcurrency(pf.concurrencyFlag),
+			)
+			if _, err := io.Copy(zw, src); err != nil {
+				return err
+			}
+			return zw.Close()
+		})
 		if err != nil {
 			panic(err)
 		}
 
-		compData, err := huff.Compress(data)
+		fmt.Printf("File compressed successfully %s\n", outputPath)
+		os.Exit(0)
+	}
+
+	if pf.compFlag {
+		checksum, err := parseChecksum(pf.checksumFlag)
+		if err != nil {
+			fmt.Println(err)
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		method, err := parseMethod(pf.methodFlag)
+		if err != nil {
+			fmt.Println(err)
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		isUTF8, err := readwrite.IsUTF8File(pf.inputFlag)
 		if err != nil {
 			panic(err)
 		}
+		alphabet := huff.AlphabetRunes
+		if !isUTF8 {
+			alphabet = huff.AlphabetBytes
+		}
 
-		outputPath := filepath.Join(filepath.Dir(*&pf.inputFlag), *&pf.outputFlag)
+		err = readwrite.StreamFile(pf.inputFlag, outputPath, func(dst io.Writer, src io.Reader) error {
+			opts := []huff.Option{
+				huff.WithChecksum(checksum),
+				huff.WithMethod(method),
+				huff.WithWindowSize(pf.windowFlag),
+				huff.WithAlphabet(alphabet),
+				huff.WithMaxCodeLength(pf.maxCodeLenFlag),
+				huff.WithBlockSize(pf.blockSizeFlag),
+				huff.WithConcurrency(pf.concurrencyFlag),
+			}
+			if pf.indexFlag {
+				opts = append(opts, huff.WithIndex())
+			}
+			if pf.rleFlag {
+				opts = append(opts, huff.WithRunLength())
+			}
+			opts = append(opts, dictOpts...)
 
-		err = readwrite.WriteFile(outputPath, compData)
+			zw := huff.NewWriter(dst, opts...)
+			if _, err := io.Copy(zw, src); err != nil {
+				return err
+			}
+			return zw.Close()
+		})
 		if err != nil {
 			panic(err)
 		}
@@ -42,19 +111,17 @@ func main() {
 		os.Exit(0)
 	}
 
-	if pf.decompFlag {
-		data, err := readwrite.ReadFile(*&pf.inputFlag)
+	if pf.decompFlag && pf.legacyFlag {
+		data, err := readwrite.ReadFile(pf.inputFlag)
 		if err != nil {
 			panic(err)
 		}
 
-		decompData, err := huff.Decompress(data)
+		decompData, err := huff.Decompress(data, huff.WithLegacyFormat())
 		if err != nil {
 			panic(err)
 		}
 
-		outputPath := filepath.Join(filepath.Dir(*&pf.inputFlag), *&pf.outputFlag)
-
 		err = readwrite.WriteFile(outputPath, decompData)
 		if err != nil {
 			panic(err)
@@ -64,16 +131,66 @@ func main() {
 		os.Exit(0)
 	}
 
+	if pf.decompFlag && pf.rangeFlag != "" {
+		start, end, err := parseRange(pf.rangeFlag)
+		if err != nil {
+			fmt.Println(err)
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		err = readwrite.ExtractFile(pf.inputFlag, outputPath, func(dst io.Writer, src io.ReaderAt, size int64) error {
+			return extractRange(dst, src, size, start, end, dictOpts...)
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("Range extracted successfully %s\n", outputPath)
+		os.Exit(0)
+	}
+
+	if pf.decompFlag {
+		err := readwrite.StreamFile(pf.inputFlag, outputPath, func(dst io.Writer, src io.Reader) error {
+			zr, err := huff.NewReader(src, append(dictOpts, huff.WithConcurrency(pf.concurrencyFlag))...)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(dst, zr); err != nil {
+				return err
+			}
+			return zr.Close()
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("File decompressed successfully %s\n", outputPath)
+		os.Exit(0)
+	}
+
 	fmt.Println("Incorrect argument!")
 	flag.Usage()
 	os.Exit(1)
 }
 
 type flags struct {
-	compFlag   bool
-	decompFlag bool
-	inputFlag  string
-	outputFlag string
+	compFlag        bool
+	decompFlag      bool
+	inputFlag       string
+	outputFlag      string
+	legacyFlag      bool
+	checksumFlag    string
+	maxCodeLenFlag  int
+	blockSizeFlag   int
+	concurrencyFlag int
+	indexFlag       bool
+	rangeFlag       string
+	methodFlag      string
+	windowFlag      int
+	gzipFlag        bool
+	rleFlag         bool
+	dictFlag        string
 }
 
 func (f *flags) parseFlags() flags {
@@ -81,8 +198,94 @@ func (f *flags) parseFlags() flags {
 	flag.StringVar(&f.outputFlag, "o", "", "Output file path to compress")
 	flag.BoolVar(&f.compFlag, "c", false, "Compress the input file to the output file")
 	flag.BoolVar(&f.decompFlag, "d", false, "Decompress the input file to the output file")
+	flag.BoolVar(&f.legacyFlag, "legacy", false, "Decompress input written in the legacy header-less format")
+	flag.StringVar(&f.checksumFlag, "checksum", "crc32", "Checksum stored when compressing: crc32, crc64 or sha256")
+	flag.IntVar(&f.maxCodeLenFlag, "max-code-len", 0, "Limit Huffman codes to this many bits when compressing, 0 for no limit")
+	flag.IntVar(&f.blockSizeFlag, "block-size", 128<<10, "Number of input bytes coded with one Huffman table when compressing")
+
+	flag.IntVar(&f.concurrencyFlag, "concurrency", runtime.NumCPU(), "Number of blocks compressed or decompressed at the same time")
+	flag.StringVar(&f.methodFlag, "method", "huffman", "Coding method used when compressing: huffman, adaptive, lz77, bwt, context, range, ans or words")
+	flag.IntVar(&f.windowFlag, "window", 32<<10, "Number of bytes back the lz77 method looks for matches, a power of two")
+	flag.BoolVar(&f.rleFlag, "rle", false, "Code runs of a repeated symbol with run symbols, huffman method only")
+	flag.BoolVar(&f.gzipFlag, "gzip", false, "Compress to gzip format, readable by gzip and other standard tools")
+	flag.BoolVar(&f.indexFlag, "index", false, "Append a block index when compressing, needed by -range")
+	flag.StringVar(&f.rangeFlag, "range", "", "Decompress only the bytes START:END of a file compressed with -index")
+	flag.StringVar(&f.dictFlag, "dict", "", "Dictionary file written by the train command, needed again to decompress")
 
 	flag.Parse()
 
 	return *f
 }
+
+func parseChecksum(name string) (huff.Checksum, error) {
+	switch name {
+	case "crc32":
+		return huff.ChecksumCRC32, nil
+	case "crc64":
+		return huff.ChecksumCRC64, nil
+	case "sha256":
+		return huff.ChecksumSHA256, nil
+	}
+
+	return 0, fmt.Errorf("unknown checksum %q", name)
+}
+
+func parseMethod(name string) (huff.Method, error) {
+	switch name {
+	case "huffman":
+		return huff.MethodHuffman, nil
+	case "adaptive":
+		return huff.MethodAdaptive, nil
+	case "lz77":
+		return huff.MethodLZ77, nil
+	case "bwt":
+		return huff.MethodBWT, nil
+	case "context":
+		return huff.MethodContext, nil
+	case "range":
+		return huff.MethodRange, nil
+	case "ans":
+		return huff.MethodANS, nil
+	case "words":
+		return huff.MethodWords, nil
+	}
+
+	return 0, fmt.Errorf("unknown method %q", name)
+}
+
+func parseRange(s string) (int64, int64, error) {
+	startStr, endStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q, want START:END", s)
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range start %q", startStr)
+	}
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range end %q", endStr)
+	}
+	if start < 0 || end < start {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+
+	return start, end, nil
+}
+
+// extractRange writes the bytes start to end of the data compressed in src
+// to dst, the range is cut short at the end of the data but must start
+// within it.
+func extractRange(dst io.Writer, src io.ReaderAt, size, start, end int64, opts ...huff.Option) error {
+	s, err := huff.OpenSeekable(src, size, opts...)
+	if err != nil {
+		return err
+	}
+	if start >= s.Size() {
+		return fmt.Errorf("range start %d is past the end of the data, %d bytes", start, s.Size())
+	}
+
+	_, err = io.Copy(dst, io.NewSectionReader(s, start, end-start))
+	return err
+}
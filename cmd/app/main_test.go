@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"compression_tool.nobletk/internal/huff"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		start, end  int64
+		expectedErr string
+	}{
+		{"Range", "10:20", 10, 20, ""},
+		{"Empty range", "5:5", 5, 5, ""},
+		{"Missing colon", "10", 0, 0, `invalid range "10", want START:END`},
+		{"Bad start", "a:20", 0, 0, `invalid range start "a"`},
+		{"Bad end", "10:", 0, 0, `invalid range end ""`},
+		{"Start overflows", "9223372036854775808:9223372036854775809", 0, 0, `invalid range start "9223372036854775808"`},
+		{"Negative start", "-1:20", 0, 0, `invalid range "-1:20"`},
+		{"End before start", "20:10", 0, 0, `invalid range "20:10"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := parseRange(tt.input)
+			if tt.expectedErr != "" {
+				if err == nil || err.Error() != tt.expectedErr {
+					t.Fatalf("parseRange() error got=%v, want=%v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if start != tt.start || end != tt.end {
+				t.Errorf("parseRange() got= %d:%d, want= %d:%d", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestExtractRange(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	compressed, err := huff.Compress(data, huff.WithIndex(), huff.WithBlockSize(64))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	src := bytes.NewReader(compressed)
+	size := int64(len(compressed))
+
+	tests := []struct {
+		name        string
+		start, end  int64
+		expected    []byte
+		expectedErr string
+	}{
+		{"Within data", 95, 205, data[95:205], ""},
+		{"Past the end", 990, 2000, data[990:], ""},
+		{"Start at the end", 1000, 1010, nil, "range start 1000 is past the end of the data, 1000 bytes"},
+		{"Start past the end", 5000, 6000, nil, "range start 5000 is past the end of the data, 1000 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := extractRange(&out, src, size, tt.start, tt.end)
+			if tt.expectedErr != "" {
+				if err == nil || err.Error() != tt.expectedErr {
+					t.Fatalf("extractRange() error got=%v, want=%v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !bytes.Equal(out.Bytes(), tt.expected) {
+				t.Errorf("extractRange() got= %q, want= %q", out.Bytes(), tt.expected)
+			}
+		})
+	}
+}
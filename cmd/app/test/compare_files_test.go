@@ -7,6 +7,9 @@ import (
 	"io"
 	"os"
 	"testing"
+
+	"compression_tool.nobletk/internal/huff"
+	"compression_tool.nobletk/internal/readwrite"
 )
 
 func TestCompareFiles(t *testing.T) {
@@ -20,6 +23,27 @@ func TestCompareFiles(t *testing.T) {
 
 }
 
+func TestDecompressLegacyFile(t *testing.T) {
+	compData, err := readwrite.ReadFile("./testdata/comp.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decompData, err := huff.Decompress(compData, huff.WithLegacyFormat())
+	if err != nil {
+		t.Fatalf("Error decompressing legacy file:%v", err)
+	}
+
+	original, err := readwrite.ReadFile("./testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(decompData, original) {
+		t.Fatal("legacy decompressed data differs from the original")
+	}
+}
+
 func compareFiles(file1, file2 string) (bool, int, error) {
 	f1, err := os.Open(file1)
 	if err != nil {
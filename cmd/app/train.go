@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"compression_tool.nobletk/internal/huff"
+	"compression_tool.nobletk/internal/readwrite"
+)
+
+// runTrain implements the train command: it builds a dictionary from the
+// sample files named by args and writes it to the -o file.
+func runTrain(args []string) {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	output := fs.String("o", "", "Dictionary file to write")
+	id := fs.Uint("id", 0, "Dictionary ID stored in compressed files, 0 derives it from the table")
+	maxCodeLen := fs.Int("max-code-len", 0, "Limit the dictionary codes to this many bits, 0 for no limit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: app train -o=dictionary_file [-id=N] sample_files...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *output == "" || fs.NArg() == 0 {
+		fmt.Println("missing argument")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *id > math.MaxUint32 {
+		fmt.Printf("invalid dictionary ID %d\n", *id)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	samples := make([][]byte, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		data, err := readwrite.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+		samples = append(samples, data)
+	}
+
+	dict, err := huff.TrainDictionary(uint32(*id), samples, *maxCodeLen)
+	if err != nil {
+		panic(err)
+	}
+	if err := readwrite.WriteFile(*output, dict.Bytes()); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Dictionary %d trained on %d files %s\n", dict.ID(), len(samples), *output)
+}
+
+// dictionaryOptions returns the option using the dictionary stored at
+// path, or none when path is empty.
+func dictionaryOptions(path string) ([]huff.Option, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := readwrite.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dict, err := huff.ParseDictionary(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []huff.Option{huff.WithDictionary(dict)}, nil
+}
@@ -0,0 +1,195 @@
+package bitio
+
+import (
+	"io"
+)
+
+const bufferSize = 4096
+
+// chunkWriter holds what the bit writers share: the accumulator of bits
+// not making a whole byte yet, the bytes buffered for the underlying writer
+// and the first write error, after which the output is dropped.
+type chunkWriter struct {
+	w     io.Writer
+	buf   []byte
+	acc   uint64
+	count uint
+	total int64
+	err   error
+}
+
+func newChunkWriter(w io.Writer) chunkWriter {
+	return chunkWriter{w: w, buf: make([]byte, 0, bufferSize)}
+}
+
+// BitsWritten returns the number of bits written so far, not counting the
+// padding added by Flush.
+func (cw *chunkWriter) BitsWritten() int64 {
+	return cw.total
+}
+
+// flush buffers last, the partial byte padded with zero bits, when bits are
+// pending and writes everything buffered to the underlying writer.
+func (cw *chunkWriter) flush(last byte) error {
+	if cw.count > 0 {
+		cw.buf = append(cw.buf, last)
+		cw.acc = 0
+		cw.count = 0
+	}
+	cw.flushBuffer()
+
+	return cw.err
+}
+
+// flushFull writes the buffer once it holds a chunk.
+func (cw *chunkWriter) flushFull() {
+	if len(cw.buf) >= bufferSize {
+		cw.flushBuffer()
+	}
+}
+
+func (cw *chunkWriter) flushBuffer() {
+	if cw.err == nil && len(cw.buf) > 0 {
+		_, cw.err = cw.w.Write(cw.buf)
+	}
+	cw.buf = cw.buf[:0]
+}
+
+// BitWriter packs bits most significant first through a 64 bit accumulator
+// and writes them to the underlying writer in chunks.
+type BitWriter struct {
+	chunkWriter
+}
+
+func NewBitWriter(w io.Writer) *BitWriter {
+	return &BitWriter{newChunkWriter(w)}
+}
+
+// WriteBits writes the low n bits of bits, n can be up to 64.
+func (bw *BitWriter) WriteBits(bits uint64, n uint8) {
+	if n > 56 {
+		bw.WriteBits(bits>>32, n-32)
+		n = 32
+	}
+	bits &= 1<<n - 1
+
+	bw.acc = bw.acc<<n | bits
+	bw.count += uint(n)
+	bw.total += int64(n)
+
+	for bw.count >= 8 {
+		bw.count -= 8
+		bw.buf = append(bw.buf, byte(bw.acc>>bw.count))
+	}
+	bw.flushFull()
+}
+
+// Flush pads the last partial byte with zero bits and writes everything
+// buffered to the underlying writer.
+func (bw *BitWriter) Flush() error {
+	return bw.flush(byte(bw.acc << (8 - bw.count)))
+}
+
+// LSBWriter packs bits least significant first, the order DEFLATE uses,
+// and writes them to the underlying writer in chunks.
+type LSBWriter struct {
+	chunkWriter
+}
+
+func NewLSBWriter(w io.Writer) *LSBWriter {
+	return &LSBWriter{newChunkWriter(w)}
+}
+
+// WriteBits writes the low n bits of bits, lowest bit first, n can be up
+// to 64.
+func (bw *LSBWriter) WriteBits(bits uint64, n uint8) {
+	if n > 56 {
+		bw.WriteBits(bits, 32)
+		bits >>= 32
+		n -= 32
+	}
+	bits &= 1<<n - 1
+
+	bw.acc |= bits << bw.count
+	bw.count += uint(n)
+	bw.total += int64(n)
+
+	for bw.count >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.count -= 8
+	}
+	bw.flushFull()
+}
+
+// Align pads the current byte with zero bits.
+func (bw *LSBWriter) Align() {
+	if bw.count > 0 {
+		bw.WriteBits(0, uint8(8-bw.count))
+	}
+}
+
+// Flush pads the last partial byte with zero bits and writes everything
+// buffered to the underlying writer.
+func (bw *LSBWriter) Flush() error {
+	return bw.flush(byte(bw.acc))
+}
+
+// BitReader reads bits most significant first from a byte slice through a
+// 64 bit accumulator, bits past the end of the data read as zero.
+type BitReader struct {
+	data  []byte
+	acc   uint64
+	count uint
+	read  int64
+}
+
+func NewBitReader(data []byte) *BitReader {
+	return &BitReader{data: data}
+}
+
+func (br *BitReader) fill() {
+	for br.count <= 56 {
+		var b byte
+		if len(br.data) > 0 {
+			b = br.data[0]
+			br.data = br.data[1:]
+		}
+		br.acc |= uint64(b) << (56 - br.count)
+		br.count += 8
+	}
+}
+
+// Peek returns the next n bits without consuming them, n can be up to 56.
+func (br *BitReader) Peek(n uint8) uint64 {
+	if br.count < uint(n) {
+		br.fill()
+	}
+
+	return br.acc >> 1 >> (63 - uint(n))
+}
+
+// Consume drops n bits that were returned by Peek.
+func (br *BitReader) Consume(n uint8) {
+	br.acc <<= n
+	br.count -= uint(n)
+	br.read += int64(n)
+}
+
+// ReadBits reads the next n bits, n can be up to 64.
+func (br *BitReader) ReadBits(n uint8) uint64 {
+	if n > 56 {
+		hi := br.ReadBits(n - 32)
+		return hi<<32 | br.ReadBits(32)
+	}
+
+	bits := br.Peek(n)
+	br.Consume(n)
+
+	return bits
+}
+
+// BitsRead returns the number of bits consumed so far.
+func (br *BitReader) BitsRead() int64 {
+	return br.read
+}
@@ -0,0 +1,171 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBitWriter(t *testing.T) {
+	var buff bytes.Buffer
+	bw := NewBitWriter(&buff)
+
+	bw.WriteBits(0b11, 2)
+	bw.WriteBits(0b101, 3)
+	bw.WriteBits(0b0, 1)
+	bw.WriteBits(0xff, 4) // only the low 4 bits are written
+	if err := bw.Flush(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []byte{0b11101011, 0b11000000}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("got= %08b, want= %08b", buff.Bytes(), expected)
+	}
+	if bw.BitsWritten() != 10 {
+		t.Errorf("BitsWritten() got= %d, want= %d", bw.BitsWritten(), 10)
+	}
+}
+
+func TestLSBWriter(t *testing.T) {
+	var buff bytes.Buffer
+	bw := NewLSBWriter(&buff)
+
+	bw.WriteBits(0b11, 2)
+	bw.WriteBits(0b101, 3)
+	bw.WriteBits(0b0, 1)
+	bw.WriteBits(0xff, 4) // only the low 4 bits are written
+	bw.Align()
+	bw.WriteBits(0x0102030405060708, 64)
+	if err := bw.Flush(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []byte{0b11010111, 0b00000011, 8, 7, 6, 5, 4, 3, 2, 1}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("got= %08b, want= %08b", buff.Bytes(), expected)
+	}
+	if bw.BitsWritten() != 80 {
+		t.Errorf("BitsWritten() got= %d, want= %d", bw.BitsWritten(), 80)
+	}
+}
+
+func TestBitReader(t *testing.T) {
+	br := NewBitReader([]byte{0b11101011, 0b11000000})
+
+	tests := []struct {
+		n        uint8
+		expected uint64
+	}{
+		{2, 0b11},
+		{3, 0b101},
+		{1, 0b0},
+		{4, 0b1111},
+		{8, 0}, // past the end reads zeros
+	}
+
+	for _, tt := range tests {
+		if actual := br.ReadBits(tt.n); actual != tt.expected {
+			t.Errorf("ReadBits(%d) got= %b, want= %b", tt.n, actual, tt.expected)
+		}
+	}
+	if br.BitsRead() != 18 {
+		t.Errorf("BitsRead() got= %d, want= %d", br.BitsRead(), 18)
+	}
+}
+
+func TestRoundTripWideValues(t *testing.T) {
+	values := []struct {
+		bits uint64
+		n    uint8
+	}{
+		{0x1, 1},
+		{0xdeadbeefcafebabe, 64},
+		{0x7f, 7},
+		{0x123456789abcdef, 57},
+		{0, 0},
+		{0x3ffffffffffffff, 58},
+		{0xabc, 12},
+	}
+
+	var buff bytes.Buffer
+	bw := NewBitWriter(&buff)
+	for i := 0; i < 1000; i++ {
+		for _, v := range values {
+			bw.WriteBits(v.bits, v.n)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	br := NewBitReader(buff.Bytes())
+	for i := 0; i < 1000; i++ {
+		for _, v := range values {
+			if actual := br.ReadBits(v.n); actual != v.bits {
+				t.Fatalf("value %d: got= %x, want= %x", i, actual, v.bits)
+			}
+		}
+	}
+}
+
+func BenchmarkBitWriter(b *testing.B) {
+	var buff bytes.Buffer
+	b.SetBytes(1 << 16)
+
+	for i := 0; i < b.N; i++ {
+		buff.Reset()
+		bw := NewBitWriter(&buff)
+		for j := 0; j < 1<<16; j++ {
+			bw.WriteBits(uint64(j), 8)
+		}
+		bw.Flush()
+	}
+}
+
+// failingWriter fails every write after the first n.
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	w.n--
+
+	return len(p), nil
+}
+
+func TestWritersKeepFirstError(t *testing.T) {
+	type bitWriter interface {
+		WriteBits(bits uint64, n uint8)
+		Flush() error
+	}
+
+	tests := []struct {
+		name string
+		new  func(w io.Writer) bitWriter
+	}{
+		{"MSB first", func(w io.Writer) bitWriter { return NewBitWriter(w) }},
+		{"LSB first", func(w io.Writer) bitWriter { return NewLSBWriter(w) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fw := &failingWriter{n: 1}
+			bw := tt.new(fw)
+			for i := 0; i < 3*bufferSize; i++ {
+				bw.WriteBits(uint64(i), 8)
+			}
+
+			if err := bw.Flush(); err == nil || err.Error() != "write failed" {
+				t.Errorf("Flush() error got=%v, want=write failed", err)
+			}
+			if fw.n != 0 {
+				t.Errorf("writes left got= %d, want= 0", fw.n)
+			}
+		})
+	}
+}
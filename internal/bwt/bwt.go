@@ -0,0 +1,262 @@
+// Package bwt implements the Burrows-Wheeler transform with the
+// move-to-front and zero run length stages that usually follow it, as in
+// bzip2.
+package bwt
+
+import (
+	"errors"
+
+	"compression_tool.nobletk/internal/runs"
+)
+
+// Transform returns the Burrows-Wheeler transform of data and the primary
+// index. The suffixes are sorted as if data ended with a sentinel smaller
+// than any byte, the sentinel is left out of the output and primary is the
+// row it was in.
+func Transform(data []byte) ([]byte, int) {
+	if len(data) == 0 {
+		return nil, 0
+	}
+
+	sa := suffixArray(data)
+	out := make([]byte, 0, len(data))
+
+	// the empty suffix sorts first and is preceded by the last byte
+	out = append(out, data[len(data)-1])
+	primary := 0
+	for i, pos := range sa {
+		if pos == 0 {
+			primary = i + 1
+			continue
+		}
+		out = append(out, data[pos-1])
+	}
+
+	return out, primary
+}
+
+// Inverse rebuilds the data passed to Transform.
+func Inverse(out []byte, primary int) ([]byte, error) {
+	n := len(out)
+	if n == 0 {
+		if primary != 0 {
+			return nil, errors.New("invalid primary index")
+		}
+		return nil, nil
+	}
+	if primary < 1 || primary > n {
+		return nil, errors.New("invalid primary index")
+	}
+
+	// row i of the last column, with the sentinel at primary
+	at := func(i int) byte {
+		if i < primary {
+			return out[i]
+		}
+		return out[i-1]
+	}
+
+	// first[c] is the first row starting with c, row 0 starts with the
+	// sentinel
+	var first [256]int
+	for _, b := range out {
+		first[b]++
+	}
+	sum := 1
+	for c := range first {
+		first[c], sum = sum, sum+first[c]
+	}
+
+	// next[i] is the row of the suffix one byte shorter than row i
+	next := make([]int32, n+1)
+	var seen [256]int
+	for i := 0; i <= n; i++ {
+		if i == primary {
+			continue
+		}
+		c := at(i)
+		next[i] = int32(first[c] + seen[c])
+		seen[c]++
+	}
+
+	data := make([]byte, n)
+	row := 0
+	for k := n - 1; k >= 0; k-- {
+		if row == primary {
+			return nil, errors.New("invalid primary index")
+		}
+		data[k] = at(row)
+		row = int(next[row])
+	}
+
+	return data, nil
+}
+
+// suffixArray sorts the suffixes of data by prefix doubling: every round
+// sorts by the ranks of the first k bytes and of the k bytes after them
+// with two counting sorts, until all ranks differ.
+func suffixArray(data []byte) []int32 {
+	n := len(data)
+	sa := make([]int32, n)
+	rank := make([]int32, n)
+	tmp := make([]int32, n)
+	cnt := make([]int32, max(256, n)+1)
+
+	for _, b := range data {
+		cnt[b]++
+	}
+	sum := int32(0)
+	for c := range cnt[:256] {
+		cnt[c], sum = sum, sum+cnt[c]
+	}
+	for i, b := range data {
+		sa[cnt[b]] = int32(i)
+		cnt[b]++
+	}
+	rank[sa[0]] = 0
+	for j := 1; j < n; j++ {
+		rank[sa[j]] = rank[sa[j-1]]
+		if data[sa[j]] != data[sa[j-1]] {
+			rank[sa[j]]++
+		}
+	}
+
+	for k := 1; rank[sa[n-1]] < int32(n-1); k <<= 1 {
+		// order by the second key: suffixes too short for one come first
+		p := 0
+		for i := n - k; i < n; i++ {
+			tmp[p] = int32(i)
+			p++
+		}
+		for _, pos := range sa {
+			if int(pos) >= k {
+				tmp[p] = pos - int32(k)
+				p++
+			}
+		}
+
+		// stable counting sort by the first key
+		classes := int(rank[sa[n-1]]) + 1
+		clear(cnt[:classes])
+		for _, r := range rank {
+			cnt[r]++
+		}
+		sum := int32(0)
+		for r := range cnt[:classes] {
+			cnt[r], sum = sum, sum+cnt[r]
+		}
+		for _, pos := range tmp {
+			sa[cnt[rank[pos]]] = pos
+			cnt[rank[pos]]++
+		}
+
+		second := func(pos int32) int32 {
+			if int(pos)+k < n {
+				return rank[int(pos)+k]
+			}
+			return -1
+		}
+		tmp[sa[0]] = 0
+		for j := 1; j < n; j++ {
+			cur, prev := sa[j], sa[j-1]
+			tmp[cur] = tmp[prev]
+			if rank[cur] != rank[prev] || second(cur) != second(prev) {
+				tmp[cur]++
+			}
+		}
+		rank, tmp = tmp, rank
+	}
+
+	return sa
+}
+
+// MoveToFront replaces every byte with its position in a list of all byte
+// values, then moves it to the front of the list. Runs of the same byte
+// become runs of zeros.
+func MoveToFront(data []byte) []byte {
+	var order [256]byte
+	for i := range order {
+		order[i] = byte(i)
+	}
+
+	out := make([]byte, len(data))
+	for i, b := range data {
+		j := 0
+		for order[j] != b {
+			j++
+		}
+		copy(order[1:j+1], order[:j])
+		order[0] = b
+		out[i] = byte(j)
+	}
+
+	return out
+}
+
+// UndoMoveToFront reverses MoveToFront.
+func UndoMoveToFront(data []byte) []byte {
+	var order [256]byte
+	for i := range order {
+		order[i] = byte(i)
+	}
+
+	out := make([]byte, len(data))
+	for i, j := range data {
+		b := order[j]
+		copy(order[1:int(j)+1], order[:j])
+		order[0] = b
+		out[i] = b
+	}
+
+	return out
+}
+
+// Symbols of the zero run alphabet. A run of zeros is written with
+// runs.Append with RunA as digit 1 and RunB as digit 2. Any other value v
+// becomes v+1.
+const (
+	RunA = 0
+	RunB = 1
+	// NumSymbols is the size of the zero run alphabet.
+	NumSymbols = 257
+)
+
+// EncodeZeroRuns codes the output of MoveToFront with the zero run
+// alphabet.
+func EncodeZeroRuns(data []byte) []uint16 {
+	out := make([]uint16, 0, len(data)/2)
+	run := 0
+	for _, b := range data {
+		if b == 0 {
+			run++
+			continue
+		}
+		out = runs.Append(out, run, RunA)
+		run = 0
+		out = append(out, uint16(b)+1)
+	}
+
+	return runs.Append(out, run, RunA)
+}
+
+// DecodeZeroRuns reverses EncodeZeroRuns.
+func DecodeZeroRuns(symbols []uint16) ([]byte, error) {
+	out := make([]byte, 0, len(symbols)*2)
+	var d runs.Decoder
+	for _, s := range symbols {
+		if s == RunA || s == RunB {
+			if err := d.Add(int(s - RunA)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if s >= NumSymbols {
+			return nil, errors.New("invalid zero run symbol")
+		}
+
+		out = append(out, make([]byte, d.Take())...)
+		out = append(out, byte(s-1))
+	}
+
+	return append(out, make([]byte, d.Take())...), nil
+}
@@ -0,0 +1,115 @@
+package bwt
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	out, primary := Transform([]byte("banana"))
+
+	// sorted suffixes: $, a$, ana$, anana$, banana$, na$, nana$
+	if string(out) != "annbaa" || primary != 4 {
+		t.Errorf("got= %q %d, want= %q %d", out, primary, "annbaa", 4)
+	}
+}
+
+func TestSuffixArray(t *testing.T) {
+	inputs := []string{"a", "banana", "mississippi", strings.Repeat("ab", 100), strings.Repeat("a", 1000)}
+	for _, input := range inputs {
+		expected := make([]int32, len(input))
+		for i := range expected {
+			expected[i] = int32(i)
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			return input[expected[i]:] < input[expected[j]:]
+		})
+
+		actual := suffixArray([]byte(input))
+		for i := range expected {
+			if actual[i] != expected[i] {
+				t.Fatalf("suffixArray(%q) got= %v, want= %v", input, actual, expected)
+			}
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	random := make([]byte, 10000)
+	rand.New(rand.NewSource(5)).Read(random)
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"Empty", nil},
+		{"Single byte", []byte{7}},
+		{"Run", bytes.Repeat([]byte{0}, 5000)},
+		{"Random", random},
+		{"Test file", data[:1<<20]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, primary := Transform(tt.input)
+			runs := EncodeZeroRuns(MoveToFront(out))
+
+			mtf, err := DecodeZeroRuns(runs)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			decoded, err := Inverse(UndoMoveToFront(mtf), primary)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !bytes.Equal(decoded, tt.input) {
+				t.Fatal("decoded data differs from the input")
+			}
+		})
+	}
+}
+
+func TestEncodeZeroRuns(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected []uint16
+	}{
+		{[]byte{0}, []uint16{RunA}},
+		{[]byte{0, 0}, []uint16{RunB}},
+		{[]byte{0, 0, 0}, []uint16{RunA, RunA}},
+		{[]byte{0, 0, 0, 0, 5, 0}, []uint16{RunB, RunA, 6, RunA}},
+		{[]byte{255, 1}, []uint16{256, 2}},
+	}
+
+	for _, tt := range tests {
+		actual := EncodeZeroRuns(tt.input)
+		if len(actual) != len(tt.expected) {
+			t.Fatalf("EncodeZeroRuns(%v) got= %v, want= %v", tt.input, actual, tt.expected)
+		}
+		for i := range actual {
+			if actual[i] != tt.expected[i] {
+				t.Fatalf("EncodeZeroRuns(%v) got= %v, want= %v", tt.input, actual, tt.expected)
+			}
+		}
+	}
+}
+
+func TestInvalidInput(t *testing.T) {
+	if _, err := Inverse([]byte("abc"), 0); err == nil {
+		t.Error("Inverse() accepted primary index 0")
+	}
+	if _, err := Inverse([]byte("abc"), 4); err == nil {
+		t.Error("Inverse() accepted primary index past the end")
+	}
+	if _, err := DecodeZeroRuns([]uint16{NumSymbols}); err == nil {
+		t.Error("DecodeZeroRuns() accepted an invalid symbol")
+	}
+}
@@ -0,0 +1,193 @@
+// Package fse implements tabled asymmetric numeral systems (tANS) coding,
+// the way the Finite State Entropy coder of zstd does. Symbols are indexes
+// into a table of normalized frequencies adding up to a power of two, the
+// size of the state table.
+package fse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"compression_tool.nobletk/internal/bitio"
+)
+
+const (
+	// MinTableLog keeps the spread step odd, so it visits every slot.
+	MinTableLog = 5
+	// MaxTableLog bounds the state table, it holds one slot per symbol of
+	// the largest alphabet.
+	MaxTableLog = 22
+)
+
+// check validates freqs against the table size.
+func check(freqs []uint32, tableLog uint8) error {
+	if tableLog < MinTableLog || tableLog > MaxTableLog {
+		return fmt.Errorf("invalid table log: %d", tableLog)
+	}
+	if len(freqs) == 0 {
+		return errors.New("no symbols")
+	}
+
+	sum := uint64(0)
+	for _, f := range freqs {
+		if f == 0 {
+			return errors.New("symbol without frequency")
+		}
+		sum += uint64(f)
+	}
+	if sum != 1<<tableLog {
+		return fmt.Errorf("frequencies add up to %d, want %d", sum, 1<<tableLog)
+	}
+
+	return nil
+}
+
+// spread lays the symbols out in the state table, every symbol taking as
+// many slots as its frequency, scattered so each symbol's slots cover the
+// table evenly.
+func spread(freqs []uint32, tableLog uint8) []uint32 {
+	size := uint32(1) << tableLog
+	mask := size - 1
+	step := size>>1 + size>>3 + 3
+
+	table := make([]uint32, size)
+	pos := uint32(0)
+	for s, f := range freqs {
+		for i := uint32(0); i < f; i++ {
+			table[pos] = uint32(s)
+			pos = (pos + step) & mask
+		}
+	}
+
+	return table
+}
+
+// Encoder codes symbols with the states of one frequency table.
+type Encoder struct {
+	tableLog uint8
+	freqs    []uint32
+	// states holds for every symbol s the next state of every value x
+	// from freqs[s] to 2*freqs[s]-1, at index x-freqs[s].
+	states [][]uint32
+}
+
+func NewEncoder(freqs []uint32, tableLog uint8) (*Encoder, error) {
+	if err := check(freqs, tableLog); err != nil {
+		return nil, err
+	}
+
+	e := &Encoder{tableLog: tableLog, freqs: freqs, states: make([][]uint32, len(freqs))}
+	for s, f := range freqs {
+		e.states[s] = make([]uint32, 0, f)
+	}
+	for u, s := range spread(freqs, tableLog) {
+		e.states[s] = append(e.states[s], 1<<tableLog+uint32(u))
+	}
+
+	return e, nil
+}
+
+// step records the bits one symbol sends.
+type step struct {
+	bits uint32
+	n    uint8
+}
+
+// Encode codes symbols and returns the payload and its size in bits. The
+// symbols are coded last to first, so the decoder reads them in order.
+func (e *Encoder) Encode(symbols []uint32) ([]byte, int, error) {
+	size := uint32(1) << e.tableLog
+	steps := make([]step, len(symbols))
+
+	state := size
+	for i := len(symbols) - 1; i >= 0; i-- {
+		s := symbols[i]
+		if int(s) >= len(e.freqs) {
+			return nil, 0, fmt.Errorf("symbol out of range: %d", s)
+		}
+
+		f := e.freqs[s]
+		n := uint8(0)
+		for state>>n >= 2*f {
+			n++
+		}
+		steps[i] = step{bits: state & (1<<n - 1), n: n}
+		state = e.states[s][state>>n-f]
+	}
+
+	var buff bytes.Buffer
+	bw := bitio.NewBitWriter(&buff)
+	bw.WriteBits(uint64(state-size), e.tableLog)
+	for _, st := range steps {
+		bw.WriteBits(uint64(st.bits), st.n)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, err
+	}
+
+	return buff.Bytes(), int(bw.BitsWritten()), nil
+}
+
+// entry is a slot of the decoding table: the symbol of the slot, the
+// number of bits to read and the state they are added to.
+type entry struct {
+	symbol uint32
+	n      uint8
+	base   uint32
+}
+
+// Decoder reads the symbols coded by an Encoder with the same table.
+type Decoder struct {
+	tableLog uint8
+	entries  []entry
+}
+
+func NewDecoder(freqs []uint32, tableLog uint8) (*Decoder, error) {
+	if err := check(freqs, tableLog); err != nil {
+		return nil, err
+	}
+
+	size := uint32(1) << tableLog
+	next := make([]uint32, len(freqs))
+	copy(next, freqs)
+
+	d := &Decoder{tableLog: tableLog, entries: make([]entry, size)}
+	for u, s := range spread(freqs, tableLog) {
+		x := next[s]
+		next[s]++
+
+		n := uint8(0)
+		for x<<n < size {
+			n++
+		}
+		d.entries[u] = entry{symbol: s, n: n, base: x<<n - size}
+	}
+
+	return d, nil
+}
+
+// Decode reads count symbols from a payload of totalBits bits.
+func (d *Decoder) Decode(payload []byte, totalBits, count int) ([]uint32, error) {
+	if totalBits < int(d.tableLog) || totalBits > 8*len(payload) {
+		return nil, errors.New("invalid payload bit count")
+	}
+
+	r := bitio.NewBitReader(payload)
+	state := uint32(r.ReadBits(d.tableLog))
+	symbols := make([]uint32, 0, min(count, 8*len(payload)+1<<16))
+	for i := 0; i < count; i++ {
+		e := d.entries[state]
+		symbols = append(symbols, e.symbol)
+		state = e.base + uint32(r.ReadBits(e.n))
+		if r.BitsRead() > int64(totalBits) {
+			return nil, errors.New("symbols exceed payload bit count")
+		}
+	}
+
+	if r.BitsRead() != int64(totalBits) || state != 0 {
+		return nil, errors.New("invalid final state")
+	}
+
+	return symbols, nil
+}
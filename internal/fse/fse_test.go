@@ -0,0 +1,159 @@
+package fse
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func randomSymbols(rng *rand.Rand, freqs []uint32, count int) []uint32 {
+	var pool []uint32
+	for s, f := range freqs {
+		for i := uint32(0); i < f; i++ {
+			pool = append(pool, uint32(s))
+		}
+	}
+
+	symbols := make([]uint32, count)
+	for i := range symbols {
+		symbols[i] = pool[rng.Intn(len(pool))]
+	}
+
+	return symbols
+}
+
+func uniform(n int, f uint32) []uint32 {
+	freqs := make([]uint32, n)
+	for i := range freqs {
+		freqs[i] = f
+	}
+
+	return freqs
+}
+
+func TestRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name     string
+		freqs    []uint32
+		tableLog uint8
+		count    int
+	}{
+		{"Empty", []uint32{16, 16}, 5, 0},
+		{"Single symbol", []uint32{32}, 5, 1000},
+		{"Uniform", []uint32{8, 8, 8, 8}, 5, 10000},
+		{"Skewed", []uint32{2000, 1, 1, 46}, 11, 10000},
+		{"Many symbols", uniform(1024, 4), 12, 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbols := randomSymbols(rng, tt.freqs, tt.count)
+
+			e, err := NewEncoder(tt.freqs, tt.tableLog)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			payload, totalBits, err := e.Encode(symbols)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			d, err := NewDecoder(tt.freqs, tt.tableLog)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			decoded, err := d.Decode(payload, totalBits, len(symbols))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !slices.Equal(decoded, symbols) {
+				t.Fatal("decoded symbols differ from the original")
+			}
+		})
+	}
+}
+
+func TestCodedSizeIsNearEntropy(t *testing.T) {
+	freqs := []uint32{1600, 560, 560, 500, 420, 320, 100, 36}
+	symbols := randomSymbols(rand.New(rand.NewSource(2)), freqs, 100000)
+
+	entropy := 0.0
+	for _, s := range symbols {
+		entropy -= math.Log2(float64(freqs[s]) / 4096)
+	}
+
+	e, err := NewEncoder(freqs, 12)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, totalBits, err := e.Encode(symbols)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if float64(totalBits) > entropy*1.01 {
+		t.Errorf("coded size got %d bits, want close to %.0f", totalBits, entropy)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	freqs := []uint32{24, 8}
+	e, err := NewEncoder(freqs, 5)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	payload, totalBits, err := e.Encode([]uint32{0, 1, 1, 0, 0, 0, 1})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	d, err := NewDecoder(freqs, 5)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name        string
+		totalBits   int
+		count       int
+		expectedErr string
+	}{
+		{"Bit count too large", 8*len(payload) + 1, 7, "invalid payload bit count"},
+		{"Too many symbols", totalBits, 100, "symbols exceed payload bit count"},
+		{"Too few symbols", totalBits, 3, "invalid final state"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := d.Decode(payload, tt.totalBits, tt.count)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Decode() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestNewDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		freqs       []uint32
+		tableLog    uint8
+		expectedErr string
+	}{
+		{"Small table", []uint32{16}, 4, "invalid table log: 4"},
+		{"No symbols", nil, 5, "no symbols"},
+		{"Zero frequency", []uint32{32, 0}, 5, "symbol without frequency"},
+		{"Wrong sum", []uint32{16, 8}, 5, "frequencies add up to 24, want 32"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDecoder(tt.freqs, tt.tableLog)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("NewDecoder() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
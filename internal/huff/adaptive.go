@@ -0,0 +1,231 @@
+package huff
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+
+	"compression_tool.nobletk/internal/bitio"
+)
+
+func init() {
+	RegisterCoder(MethodAdaptive, adaptiveCoder{})
+}
+
+// adaptiveCoder codes blocks in one pass with an adaptive tree starting
+// out empty, its model is empty as the decoder rebuilds the tree.
+type adaptiveCoder struct{}
+
+func (adaptiveCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	return nil, nil
+}
+
+func (adaptiveCoder) WriteModel(m Model) []byte {
+	return nil
+}
+
+func (adaptiveCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	bitBuff, totalBits, err := encodeAdaptive(data, cfg.Alphabet)
+
+	return bitBuff.Bytes(), totalBits, err
+}
+
+func (adaptiveCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	if len(b) != 0 {
+		return nil, errors.New("adaptive block with a model")
+	}
+
+	return nil, nil
+}
+
+func (adaptiveCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	return decodeAdaptive(payload, totalBits, cfg.Alphabet)
+}
+
+// adaptiveNode is a node of the FGK tree. The leaf without a symbol is the
+// NYT (not yet transmitted) node, which escapes symbols seen for the first
+// time.
+type adaptiveNode struct {
+	weight int
+	// rank is the position of the node in adaptiveTree.order.
+	rank   int
+	char   rune
+	parent *adaptiveNode
+	left   *adaptiveNode
+	right  *adaptiveNode
+}
+
+func (n *adaptiveNode) isLeaf() bool {
+	return n.left == nil
+}
+
+// adaptiveTree is an adaptive Huffman tree updated with the FGK algorithm.
+// The encoder and the decoder apply the same updates after every symbol,
+// so their trees stay identical without the tree being stored.
+type adaptiveTree struct {
+	root *adaptiveNode
+	nyt  *adaptiveNode
+	// order lists the nodes by decreasing rank, weights never increase
+	// along it (the sibling property).
+	order  []*adaptiveNode
+	leaves map[rune]*adaptiveNode
+	// symbolBits is the size of a symbol sent after the NYT code.
+	symbolBits uint8
+	path       []byte
+}
+
+func newAdaptiveTree(alphabet Alphabet) *adaptiveTree {
+	root := &adaptiveNode{}
+	t := &adaptiveTree{
+		root:       root,
+		nyt:        root,
+		order:      []*adaptiveNode{root},
+		leaves:     make(map[rune]*adaptiveNode),
+		symbolBits: 21,
+	}
+	if alphabet == AlphabetBytes {
+		t.symbolBits = 8
+	}
+
+	return t
+}
+
+// add splits the NYT node into a new NYT node and a leaf for char, and
+// returns the leaf.
+func (t *adaptiveTree) add(char rune) *adaptiveNode {
+	parent := t.nyt
+	leaf := &adaptiveNode{char: char, parent: parent, rank: len(t.order)}
+	nyt := &adaptiveNode{parent: parent, rank: len(t.order) + 1}
+	parent.left, parent.right = nyt, leaf
+
+	t.order = append(t.order, leaf, nyt)
+	t.leaves[char] = leaf
+	t.nyt = nyt
+
+	return leaf
+}
+
+// update increments the weight of n and its ancestors. Before a node is
+// incremented it is swapped with the highest ranked node of the same
+// weight, which keeps the sibling property.
+func (t *adaptiveTree) update(n *adaptiveNode) {
+	for ; n != nil; n = n.parent {
+		leader := n
+		for leader.rank > 0 && t.order[leader.rank-1].weight == n.weight {
+			leader = t.order[leader.rank-1]
+		}
+		if leader != n && leader != n.parent {
+			t.swap(n, leader)
+		}
+		n.weight++
+	}
+}
+
+// swap exchanges the places of two nodes in the tree and in the order,
+// neither may be an ancestor of the other.
+func (t *adaptiveTree) swap(a, b *adaptiveNode) {
+	pa, pb := a.parent, b.parent
+	if pa == pb {
+		pa.left, pa.right = pa.right, pa.left
+	} else {
+		pa.replace(a, b)
+		pb.replace(b, a)
+		a.parent, b.parent = pb, pa
+	}
+
+	t.order[a.rank], t.order[b.rank] = b, a
+	a.rank, b.rank = b.rank, a.rank
+}
+
+func (n *adaptiveNode) replace(old, child *adaptiveNode) {
+	if n.left == old {
+		n.left = child
+	} else {
+		n.right = child
+	}
+}
+
+// writeCode writes the path from the root to n, a left branch is a 0 bit.
+func (t *adaptiveTree) writeCode(bw *bitio.BitWriter, n *adaptiveNode) {
+	t.path = t.path[:0]
+	for ; n.parent != nil; n = n.parent {
+		bit := byte(0)
+		if n.parent.right == n {
+			bit = 1
+		}
+		t.path = append(t.path, bit)
+	}
+
+	for i := len(t.path) - 1; i >= 0; i-- {
+		bw.WriteBits(uint64(t.path[i]), 1)
+	}
+}
+
+// encodeAdaptive codes data in one pass, starting from an empty tree.
+func encodeAdaptive(data []byte, alphabet Alphabet) (bytes.Buffer, int, error) {
+	var bitBuff bytes.Buffer
+	bw := bitio.NewBitWriter(&bitBuff)
+	t := newAdaptiveTree(alphabet)
+
+	for i := 0; i < len(data); {
+		char, sz, err := alphabet.next(data[i:])
+		if err != nil {
+			return bytes.Buffer{}, 0, err
+		}
+		i += sz
+
+		leaf, ok := t.leaves[char]
+		if ok {
+			t.writeCode(bw, leaf)
+		} else {
+			t.writeCode(bw, t.nyt)
+			bw.WriteBits(uint64(char), t.symbolBits)
+			leaf = t.add(char)
+		}
+		t.update(leaf)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return bytes.Buffer{}, 0, err
+	}
+
+	return bitBuff, int(bw.BitsWritten()), nil
+}
+
+// decodeAdaptive reverses encodeAdaptive, reading codes until totalBits
+// bits are consumed.
+func decodeAdaptive(enc []byte, totalBits int, alphabet Alphabet) ([]byte, error) {
+	br := bitio.NewBitReader(enc)
+	t := newAdaptiveTree(alphabet)
+	decoded := make([]byte, 0, len(enc))
+
+	for br.BitsRead() < int64(totalBits) {
+		n := t.root
+		for !n.isLeaf() {
+			if br.ReadBits(1) == 0 {
+				n = n.left
+			} else {
+				n = n.right
+			}
+		}
+
+		if n == t.nyt {
+			char := rune(br.ReadBits(t.symbolBits))
+			if !utf8.ValidRune(char) {
+				return nil, errors.New("invalid symbol in payload")
+			}
+			if _, ok := t.leaves[char]; ok {
+				return nil, errors.New("invalid symbol in payload")
+			}
+			n = t.add(char)
+		}
+		if br.BitsRead() > int64(totalBits) {
+			return nil, errors.New("last code exceeds payload bit count")
+		}
+
+		decoded = alphabet.appendSymbol(decoded, n.char)
+		t.update(n)
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,79 @@
+package huff
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestAdaptiveCloseToStatic(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	static, err := Compress(data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	adaptive, err := Compress(data, WithMethod(MethodAdaptive))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// one pass coding costs a little, but stays within 2% of two pass
+	if len(adaptive)*100 > len(static)*102 {
+		t.Fatalf("adaptive output is %d bytes, static output %d", len(adaptive), len(static))
+	}
+}
+
+func TestAdaptiveTreeSiblingProperty(t *testing.T) {
+	tree := newAdaptiveTree(AlphabetBytes)
+	for _, char := range []byte("abracadabra mississippi fibonacci") {
+		leaf, ok := tree.leaves[rune(char)]
+		if !ok {
+			leaf = tree.add(rune(char))
+		}
+		tree.update(leaf)
+
+		for i, n := range tree.order {
+			assertEqual(t, n.rank, i)
+			if i > 0 && n.weight > tree.order[i-1].weight {
+				t.Fatalf("weight of rank %d increases after %q", i, char)
+			}
+			if !n.isLeaf() {
+				assertEqual(t, n.weight, n.left.weight+n.right.weight)
+			}
+		}
+	}
+}
+
+func TestAdaptiveMethodMismatch(t *testing.T) {
+	compressed, err := Compress(bytes.Repeat([]byte("abc"), 100), WithMethod(MethodAdaptive))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	compressed[7] = byte(MethodHuffman)
+
+	_, err = Decompress(compressed)
+	assertEqual(t, err.Error(), "block type 7 does not match method 0")
+
+	_, err = Compress([]byte("abc"), WithMethod(MethodAdaptive), WithMaxCodeLength(8))
+	assertEqual(t, err.Error(), "max code length is not supported by method 1")
+}
+
+func TestAdaptiveSeekable(t *testing.T) {
+	data := bytes.Repeat([]byte("adaptive blocks are independent. "), 300)
+	compressed := compressIndexed(t, data, WithMethod(MethodAdaptive), WithBlockSize(1000))
+
+	s, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	p := make([]byte, 1500)
+	if _, err := s.ReadAt(p, 4321); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, p, data[4321:4321+1500])
+}
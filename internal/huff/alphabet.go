@@ -0,0 +1,41 @@
+package huff
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// Alphabet selects how the input is split into symbols, it is kept in the
+// header flags next to the checksum.
+type Alphabet byte
+
+const (
+	// AlphabetRunes codes every UTF-8 encoded rune as one symbol, the input
+	// must be valid UTF-8.
+	AlphabetRunes Alphabet = iota
+	// AlphabetBytes codes every byte as one of 256 symbols and accepts any
+	// input.
+	AlphabetBytes
+)
+
+// next returns the symbol at the start of b and its size in bytes.
+func (a Alphabet) next(b []byte) (rune, int, error) {
+	if a == AlphabetBytes {
+		return rune(b[0]), 1, nil
+	}
+
+	char, sz := utf8.DecodeRune(b)
+	if char == utf8.RuneError && sz <= 1 {
+		return 0, 0, errors.New("invalid UTF-8 encoding")
+	}
+
+	return char, sz, nil
+}
+
+func (a Alphabet) appendSymbol(dst []byte, char rune) []byte {
+	if a == AlphabetBytes {
+		return append(dst, byte(char))
+	}
+
+	return utf8.AppendRune(dst, char)
+}
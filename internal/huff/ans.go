@@ -0,0 +1,147 @@
+package huff
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+
+	"compression_tool.nobletk/internal/fse"
+)
+
+// ansTableLog is the state table size of blocks with enough symbols to
+// fill it, 2^11 slots as in zstd.
+const ansTableLog = 11
+
+func init() {
+	RegisterCoder(MethodANS, ansCoder{})
+}
+
+// chooseTableLog picks the state table size of a block of count symbols
+// over an alphabet of n symbols. Small blocks get smaller tables, large
+// alphabets larger ones so every symbol has a slot.
+func chooseTableLog(n, count int) uint8 {
+	tableLog := min(ansTableLog, bits.Len(uint(count)))
+	tableLog = max(tableLog, bits.Len(uint(n))+1, fse.MinTableLog)
+
+	return uint8(min(tableLog, fse.MaxTableLog))
+}
+
+// ansModel holds the frequencies of a block scaled to the size of the
+// state table and its symbol count.
+type ansModel struct {
+	freqMap  FrequencyMap
+	count    int
+	tableLog uint8
+	m        rangeModel
+}
+
+// ansCoder codes blocks with a tANS coder whose state table is built from
+// the symbol frequencies of the block.
+type ansCoder struct{}
+
+func (ansCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	freqMap, err := getSymbolsFrequency(data, cfg.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	if len(freqMap) == 0 {
+		return &ansModel{}, nil
+	}
+
+	count := 0
+	for _, freq := range freqMap {
+		count += freq
+	}
+	tableLog := chooseTableLog(len(freqMap), count)
+	freqMap = scaleFrequencies(freqMap, 1<<tableLog)
+
+	return &ansModel{freqMap: freqMap, count: count, tableLog: tableLog, m: newRangeModel(freqMap)}, nil
+}
+
+// WriteModel stores the symbol count, then for a block with symbols the
+// table log and the frequencies.
+func (ansCoder) WriteModel(m Model) []byte {
+	am := m.(*ansModel)
+	b := binary.AppendUvarint(nil, uint64(am.count))
+	if am.count == 0 {
+		return b
+	}
+
+	b = append(b, am.tableLog)
+
+	return append(b, serializeFrequencies(am.freqMap)...)
+}
+
+func (ansCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	am := m.(*ansModel)
+	if am.count == 0 {
+		return nil, 0, nil
+	}
+
+	symbols, err := splitSymbols(data, cfg.Alphabet)
+	if err != nil {
+		return nil, 0, err
+	}
+	index := make(map[rune]uint32, len(am.m.symbols))
+	for i, char := range am.m.symbols {
+		index[char] = uint32(i)
+	}
+	indexes := make([]uint32, len(symbols))
+	for i, char := range symbols {
+		indexes[i] = index[char]
+	}
+
+	e, err := fse.NewEncoder(am.m.freqs, am.tableLog)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return e.Encode(indexes)
+}
+
+func (ansCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	count, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, errors.New("invalid symbol count")
+	}
+	if count > maxSymbolCount {
+		return nil, errors.New("symbol count is too large")
+	}
+	b = b[n:]
+	if count == 0 {
+		return &ansModel{}, nil
+	}
+	if len(b) == 0 {
+		return nil, errors.New("symbols without frequencies")
+	}
+
+	freqMap, err := deserializeFrequencies(b[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &ansModel{freqMap: freqMap, count: int(count), tableLog: b[0], m: newRangeModel(freqMap)}, nil
+}
+
+func (ansCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	am := m.(*ansModel)
+	if am.count == 0 {
+		return nil, nil
+	}
+
+	d, err := fse.NewDecoder(am.m.freqs, am.tableLog)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := d.Decode(payload, totalBits, am.count)
+	if err != nil {
+		return nil, err
+	}
+
+	decompressed := make([]byte, 0, len(indexes))
+	for _, i := range indexes {
+		decompressed = cfg.Alphabet.appendSymbol(decompressed, am.m.symbols[i])
+	}
+
+	return decompressed, nil
+}
@@ -0,0 +1,93 @@
+package huff
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestANSBeatsHuffman(t *testing.T) {
+	binary := make([]byte, 100000)
+	rng := rand.New(rand.NewSource(2))
+	for i := range binary {
+		if rng.Intn(20) == 0 {
+			binary[i] = 'b'
+		} else {
+			binary[i] = 'a'
+		}
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		// ratio is the largest tANS output size accepted, in percent of
+		// the Huffman output size.
+		ratio int
+	}{
+		{"Skewed letters", skewedText(100000), 100},
+		{"Two symbols", binary, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			huffman, err := Compress(tt.input)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			ans, err := Compress(tt.input, WithMethod(MethodANS))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if len(ans)*100 >= len(huffman)*tt.ratio {
+				t.Errorf("tANS output got %d bytes, want less than %d%% of %d", len(ans), tt.ratio, len(huffman))
+			}
+		})
+	}
+}
+
+func TestChooseTableLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		symbols  int
+		count    int
+		expected uint8
+	}{
+		{"Tiny block", 1, 1, 5},
+		{"Small block", 20, 300, 9},
+		{"Text", 100, 100000, 11},
+		{"Many symbols", 3000, 100000, 13},
+		{"Every rune", 0x110000, 1 << 30, 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertEqual(t, chooseTableLog(tt.symbols, tt.count), tt.expected)
+		})
+	}
+}
+
+func TestDecodeANSErrors(t *testing.T) {
+	model, payload, totalBits := codeBlock(t, ansCoder{}, []byte("aaaa bbb cc d"), CoderConfig{})
+
+	tests := []struct {
+		name        string
+		model       []byte
+		expectedErr string
+	}{
+		{"Missing frequencies", []byte{13}, "symbols without frequencies"},
+		{"Bad table log", append([]byte{13, 4}, model[2:]...), "invalid table log: 4"},
+		{"Wrong count", append([]byte{14}, model[1:]...), "symbols exceed payload bit count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ansCoder{}.ReadModel(tt.model, CoderConfig{})
+			if err == nil {
+				_, err = ansCoder{}.Decode(m, payload, totalBits, CoderConfig{})
+			}
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Decode() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
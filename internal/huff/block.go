@@ -0,0 +1,475 @@
+package huff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// blockType is the first byte of every block.
+type blockType byte
+
+// Types 3, 5 and 6 were the adaptive, LZ77 and BWT blocks of earlier
+// format versions, those methods are coded blocks now.
+const (
+	// blockEnd closes the sequence of blocks, the trailer follows it.
+	blockEnd blockType = 0
+	// blockNewTable stores its own code length table.
+	blockNewTable blockType = 1
+	// blockReuseTable is coded with the table of the last block that
+	// stored one.
+	blockReuseTable blockType = 2
+	// blockStored holds the input as is, for data coding would expand.
+	blockStored blockType = 4
+	// blockCoded stores the model of the Coder registered for the method
+	// of the stream.
+	blockCoded blockType = 7
+)
+
+// maxSectionSize bounds the lengths read from a block so a corrupted
+// stream fails instead of allocating without limit.
+const maxSectionSize = 1 << 40
+
+var ErrTruncatedHeader = errors.New("truncated header")
+
+// block is a parsed block before it is decoded.
+type block struct {
+	typ blockType
+	// table is the code length table of a Huffman block or the model of
+	// a coded block.
+	table     []byte
+	payload   []byte
+	totalBits int
+}
+
+func writeBlock(buff *bytes.Buffer, b block) {
+	buff.WriteByte(byte(b.typ))
+	if b.typ == blockEnd {
+		return
+	}
+	if b.typ == blockStored {
+		buff.Write(binary.AppendUvarint(nil, uint64(len(b.payload))))
+		buff.Write(b.payload)
+		return
+	}
+
+	if b.typ == blockNewTable || b.typ == blockCoded {
+		buff.Write(binary.AppendUvarint(nil, uint64(len(b.table))))
+		buff.Write(b.table)
+	}
+	buff.Write(binary.AppendUvarint(nil, uint64(b.totalBits)))
+	buff.Write(b.payload)
+}
+
+// readBlock parses the block at the start of r, using the lengths stored in
+// front of every section.
+func readBlock(r *bufio.Reader) (block, error) {
+	var b block
+
+	typ, err := r.ReadByte()
+	if err != nil {
+		return block{}, truncated(err, fmt.Errorf("%w: block type", ErrTruncatedHeader))
+	}
+	b.typ = blockType(typ)
+
+	switch b.typ {
+	case blockEnd:
+		return b, nil
+	case blockNewTable:
+		if b.table, err = readTable(r, "table"); err != nil {
+			return block{}, err
+		}
+	case blockCoded:
+		if b.table, err = readTable(r, "model"); err != nil {
+			return block{}, err
+		}
+	case blockReuseTable:
+	case blockStored:
+		storedLen, err := binary.ReadUvarint(r)
+		if err != nil {
+			return block{}, truncated(err, fmt.Errorf("%w: stored length", ErrTruncatedHeader))
+		}
+		payload, n, err := readSection(r, storedLen)
+		if err != nil {
+			return block{}, truncated(err, fmt.Errorf("truncated stored data: needs %d bytes, %d left", storedLen, n))
+		}
+		b.payload = payload
+		return b, nil
+	default:
+		return block{}, fmt.Errorf("unknown block type: %d", b.typ)
+	}
+
+	totalBits, err := binary.ReadUvarint(r)
+	if err != nil {
+		return block{}, truncated(err, fmt.Errorf("%w: payload bit count", ErrTruncatedHeader))
+	}
+	if totalBits > 8*maxSectionSize {
+		return block{}, fmt.Errorf("%w: payload bit count is too large", ErrTruncatedHeader)
+	}
+	payloadLen := (totalBits + 7) / 8
+	payload, n, err := readSection(r, payloadLen)
+	if err != nil {
+		return block{}, truncated(err, fmt.Errorf("truncated payload: needs %d bytes, %d left", payloadLen, n))
+	}
+	b.payload = payload
+	b.totalBits = int(totalBits)
+
+	return b, nil
+}
+
+// readTable reads a table stored after its length.
+func readTable(r *bufio.Reader, name string) ([]byte, error) {
+	tableLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, truncated(err, fmt.Errorf("%w: %s length", ErrTruncatedHeader, name))
+	}
+	table, n, err := readSection(r, tableLen)
+	if err != nil {
+		return nil, truncated(err, fmt.Errorf("%w: %s needs %d bytes, %d left", ErrTruncatedHeader, name, tableLen, n))
+	}
+
+	return table, nil
+}
+
+// readSection reads n bytes, growing the buffer as data arrives rather
+// than trusting n up front.
+func readSection(r io.Reader, n uint64) ([]byte, int, error) {
+	if n > maxSectionSize {
+		return nil, 0, errors.New("section length is too large")
+	}
+
+	var buff bytes.Buffer
+	m, err := io.CopyN(&buff, r, int64(n))
+	if err != nil {
+		return nil, int(m), err
+	}
+
+	return buff.Bytes(), int(m), nil
+}
+
+// truncated replaces an end of input error with the precise error for the
+// field being read.
+func truncated(err, precise error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return precise
+	}
+
+	return err
+}
+
+// blockEncoder remembers the last table stored in the stream, so a later
+// block with a similar distribution can reuse it.
+type blockEncoder struct {
+	opts  options
+	model *huffmanModel
+}
+
+// blockSize returns the size of a block storing a table of tableLen bytes,
+// or none when tableLen is negative, and a payload of totalBits bits.
+func blockSize(tableLen, totalBits int) int {
+	size := 1 + uvarintLen(totalBits) + (totalBits+7)/8
+	if tableLen >= 0 {
+		size += uvarintLen(tableLen) + tableLen
+	}
+
+	return size
+}
+
+// storedSize returns the size of a stored block holding n bytes.
+func storedSize(n int) int {
+	return 1 + uvarintLen(n) + n
+}
+
+func uvarintLen(n int) int {
+	return len(binary.AppendUvarint(nil, uint64(n)))
+}
+
+// blockPlan carries one block through the encoding steps: analyze and
+// encode only touch the plan and may run concurrently, choose must see the
+// plans in stream order.
+type blockPlan struct {
+	data []byte
+	// model is the block's own Huffman model, then the one it is coded
+	// with once choose has run.
+	model *huffmanModel
+	table []byte
+	// newBits is the payload size with the block's own table.
+	newBits int
+	typ     blockType
+	out     bytes.Buffer
+}
+
+// analyzeBlock builds the Huffman model of data. Blocks of other methods
+// build their models while they are encoded. A block with more symbols than
+// the max code length allows is stored.
+func analyzeBlock(data []byte, o options) (*blockPlan, error) {
+	if o.method != MethodHuffman {
+		return &blockPlan{data: data, typ: o.method.blockType()}, nil
+	}
+
+	c := coders[MethodHuffman]
+	m, err := c.BuildModel(data, o.coderConfig())
+	if errors.Is(err, errLimitTooSmall) {
+		return &blockPlan{data: data, typ: blockStored}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &blockPlan{
+		data:  data,
+		model: m.(*huffmanModel),
+		table: c.WriteModel(m),
+	}
+	p.newBits, _ = codedSize(p.model.freqMap, p.model.lengths)
+
+	return p, nil
+}
+
+// choose codes the block with its own table or the previous one, whichever
+// is smaller, or stores it when neither is smaller than the input.
+func (e *blockEncoder) choose(p *blockPlan) {
+	if e.opts.method != MethodHuffman || p.typ == blockStored {
+		return
+	}
+
+	p.typ = blockNewTable
+	size := blockSize(len(p.table), p.newBits)
+	if e.model != nil {
+		if reuseBits, ok := codedSize(p.model.freqMap, e.model.lengths); ok && reuseBits <= p.newBits+len(p.table)*8 {
+			p.typ = blockReuseTable
+			size = blockSize(-1, reuseBits)
+		}
+	}
+
+	switch {
+	case size >= storedSize(len(p.data)):
+		p.typ = blockStored
+	case p.typ == blockReuseTable:
+		p.model = e.model
+	default:
+		e.model = p.model
+	}
+}
+
+// encode writes the chosen block to p.out. A block of a method other than
+// MethodHuffman is stored instead when coding does not make it smaller or
+// its symbols do not fit the max code length.
+func (p *blockPlan) encode(o options) error {
+	var b block
+	var err error
+	switch p.typ {
+	case blockStored:
+		writeBlock(&p.out, block{typ: blockStored, payload: p.data})
+		return nil
+	case blockCoded:
+		b, err = encodeCoded(p.data, o)
+		if errors.Is(err, errLimitTooSmall) {
+			p.typ = blockStored
+			writeBlock(&p.out, block{typ: blockStored, payload: p.data})
+			return nil
+		}
+	default:
+		b = block{typ: p.typ}
+		b.payload, b.totalBits, err = coders[MethodHuffman].Encode(p.model, p.data, o.coderConfig())
+		if p.typ == blockNewTable {
+			b.table = p.table
+		}
+	}
+	if err != nil {
+		return err
+	}
+	writeBlock(&p.out, b)
+
+	if o.method != MethodHuffman && p.out.Len() >= storedSize(len(p.data)) {
+		p.typ = blockStored
+		p.out.Reset()
+		writeBlock(&p.out, block{typ: blockStored, payload: p.data})
+	}
+
+	return nil
+}
+
+// encodeCoded codes data with the Coder of the method, storing its model
+// in the block.
+func encodeCoded(data []byte, o options) (block, error) {
+	c, err := coderFor(o.method)
+	if err != nil {
+		return block{}, err
+	}
+
+	cfg := o.coderConfig()
+	m, err := c.BuildModel(data, cfg)
+	if err != nil {
+		return block{}, err
+	}
+	payload, totalBits, err := c.Encode(m, data, cfg)
+	if err != nil {
+		return block{}, err
+	}
+
+	return block{typ: blockCoded, table: c.WriteModel(m), payload: payload, totalBits: totalBits}, nil
+}
+
+// encode writes data as one block.
+func (e *blockEncoder) encode(buff *bytes.Buffer, data []byte) error {
+	p, err := analyzeBlock(data, e.opts)
+	if err != nil {
+		return err
+	}
+
+	e.choose(p)
+	if err := p.encode(e.opts); err != nil {
+		return err
+	}
+	buff.Write(p.out.Bytes())
+
+	return nil
+}
+
+// encodeAll encodes consecutive blocks on up to workers goroutines, the
+// output is the same as encoding them one by one. The returned plans hold
+// the encoded blocks in order.
+func (e *blockEncoder) encodeAll(buff *bytes.Buffer, blocks [][]byte, workers int) ([]*blockPlan, error) {
+	plans := make([]*blockPlan, len(blocks))
+	err := parallel(len(blocks), workers, func(i int) error {
+		var err error
+		plans[i], err = analyzeBlock(blocks[i], e.opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range plans {
+		e.choose(p)
+	}
+
+	err = parallel(len(plans), workers, func(i int) error {
+		return plans[i].encode(e.opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range plans {
+		buff.Write(p.out.Bytes())
+	}
+
+	return plans, nil
+}
+
+// codedSize returns the payload size in bits when freqMap is coded with
+// lengths, ok is false when a symbol has no code.
+func codedSize(freqMap map[rune]int, lengths map[rune]int) (int, bool) {
+	if lengths == nil {
+		return 0, false
+	}
+
+	size := 0
+	for char, freq := range freqMap {
+		length, exists := lengths[char]
+		if !exists {
+			return 0, false
+		}
+		size += freq * length
+	}
+
+	return size, true
+}
+
+// blockDecoder decodes the blocks of one stream and keeps the table in
+// effect for blocks that reuse it.
+type blockDecoder struct {
+	h     header
+	model Model
+}
+
+// modelFor returns the Huffman model b is coded with, reading it when b
+// stores one. Blocks of other methods carry what they need and get none.
+func (d *blockDecoder) modelFor(b block) (Model, error) {
+	if b.typ == blockStored {
+		return nil, nil
+	}
+	if m := d.h.method; m != MethodHuffman {
+		if b.typ != m.blockType() {
+			return nil, fmt.Errorf("block type %d does not match method %d", b.typ, m)
+		}
+		return nil, nil
+	}
+
+	switch b.typ {
+	case blockNewTable:
+		m, err := coders[MethodHuffman].ReadModel(b.table, d.h.coderConfig())
+		if err != nil {
+			return nil, err
+		}
+		d.model = m
+	case blockReuseTable:
+		if d.model == nil {
+			return nil, errors.New("block reuses a table before one was stored")
+		}
+	default:
+		return nil, fmt.Errorf("block type %d does not match method %d", b.typ, MethodHuffman)
+	}
+
+	return d.model, nil
+}
+
+func (d *blockDecoder) decode(b block) ([]byte, error) {
+	m, err := d.modelFor(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodePayload(b, m, d.h)
+}
+
+// decodePayload decodes the payload of b with the Huffman model m, coded
+// blocks carry their model. A stored payload is returned as is.
+func decodePayload(b block, m Model, h header) ([]byte, error) {
+	if b.typ == blockStored {
+		return b.payload, nil
+	}
+
+	c, err := coderFor(h.method)
+	if err != nil {
+		return nil, err
+	}
+	cfg := h.coderConfig()
+	if b.typ == blockCoded {
+		if m, err = c.ReadModel(b.table, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.Decode(m, b.payload, b.totalBits, cfg)
+}
+
+// decodeAll decodes consecutive blocks on up to workers goroutines. Tables
+// are resolved in stream order first, which makes the payloads independent.
+func (d *blockDecoder) decodeAll(blocks []block, workers int) ([][]byte, error) {
+	models := make([]Model, len(blocks))
+	for i, b := range blocks {
+		var err error
+		models[i], err = d.modelFor(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	decoded := make([][]byte, len(blocks))
+	err := parallel(len(blocks), workers, func(i int) error {
+		var err error
+		decoded[i], err = decodePayload(blocks[i], models[i], d.h)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
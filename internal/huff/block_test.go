@@ -0,0 +1,201 @@
+package huff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestReadBlock(t *testing.T) {
+	input := []byte{1, 9, 32, 1, 2, 64, 4, 2, 2, 3, 3, 29, 85, 42, 54, 56}
+
+	r := bufio.NewReader(bytes.NewReader(append(input, 2, 3, 0xe0)))
+	b, err := readBlock(r)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, b.typ, blockNewTable)
+	assertEqualBytes(t, b.table, []byte{32, 1, 2, 64, 4, 2, 2, 3, 3})
+	assertEqualBytes(t, b.payload, []byte{85, 42, 54, 56})
+	assertEqual(t, b.totalBits, 29)
+
+	b, err = readBlock(r)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, b.typ, blockReuseTable)
+	assertEqual(t, len(b.table), 0)
+	assertEqualBytes(t, b.payload, []byte{0xe0})
+	assertEqual(t, b.totalBits, 3)
+}
+
+func TestReadBlockInvalidInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Empty input", []byte{}, "truncated header: block type"},
+		{"Unknown type", []byte{8}, "unknown block type: 8"},
+		{"Missing table length", []byte{1}, "truncated header: table length"},
+		{"Short table", []byte{1, 14, 0, 0, 0}, "truncated header: table needs 14 bytes, 3 left"},
+		{"Missing bit count", []byte{1, 2, 1, 'a'}, "truncated header: payload bit count"},
+		{"Short payload", []byte{1, 2, 1, 'a', 29, 255}, "truncated payload: needs 4 bytes, 1 left"},
+		{"Huge payload", []byte{2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, "truncated header: payload bit count is too large"},
+		{"Overflowing bit count", []byte{2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, "truncated header: payload bit count is too large"},
+		{"Missing stored length", []byte{4}, "truncated header: stored length"},
+		{"Short stored data", []byte{4, 5, 'a', 'b'}, "truncated stored data: needs 5 bytes, 2 left"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readBlock(bufio.NewReader(bytes.NewReader(tt.input)))
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("readBlock() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestBlockEncoderReusesTable(t *testing.T) {
+	enc := blockEncoder{opts: newOptions(nil)}
+
+	var first, second, third bytes.Buffer
+	if err := enc.encode(&first, []byte(strings.Repeat("abcabd", 50))); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := enc.encode(&second, []byte(strings.Repeat("abcdab", 50))); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := enc.encode(&third, []byte(strings.Repeat("xyz", 50))); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, blockType(first.Bytes()[0]), blockNewTable)
+	assertEqual(t, blockType(second.Bytes()[0]), blockReuseTable)
+	assertEqual(t, blockType(third.Bytes()[0]), blockNewTable)
+}
+
+func TestBlockDecoderReuseWithoutTable(t *testing.T) {
+	dec := blockDecoder{}
+
+	_, err := dec.decode(block{typ: blockReuseTable, payload: []byte{0}, totalBits: 1})
+	expectedErr := "block reuses a table before one was stored"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("decode() error got=%v, want=%v", err, expectedErr)
+	}
+}
+
+func TestCompressShiftingDistribution(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString(strings.Repeat("func main() { return x[i] + y[j] }\n", 2000))
+	input.WriteString(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 2000))
+	input.WriteString(strings.Repeat("| 12.50 | 3,400 | 0.75 |\n", 2000))
+
+	small, err := Compress(input.Bytes(), WithBlockSize(16<<10))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	large, err := Compress(input.Bytes(), WithBlockSize(1<<30))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(small) >= len(large) {
+		t.Errorf("per block tables got= %d bytes, single table= %d bytes", len(small), len(large))
+	}
+
+	for _, compressed := range [][]byte{small, large} {
+		decompressed, err := Decompress(compressed)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		assertEqualBytes(t, decompressed, input.Bytes())
+	}
+}
+
+func TestStoredBlocksBoundExpansion(t *testing.T) {
+	random := make([]byte, 300<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	compressed, err := Compress([]byte(strings.Repeat("abcd", 1000)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		opts  []Option
+	}{
+		{name: "Random bytes", input: random, opts: []Option{WithAlphabet(AlphabetBytes)}},
+		{name: "Random adaptive", input: random, opts: []Option{WithAlphabet(AlphabetBytes), WithMethod(MethodAdaptive)}},
+		{name: "Compressed data", input: compressed, opts: []Option{WithAlphabet(AlphabetBytes)}},
+		{name: "Short text", input: []byte("hi")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Compress(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			// header, end block and CRC32 trailer, then a type byte and a
+			// length of at most 3 bytes per 128 KiB block
+			blocks := (len(tt.input) + defaultBlockSize - 1) / defaultBlockSize
+			bound := len(tt.input) + headerSize + 1 + 12 + blocks*4
+			if len(output) > bound {
+				t.Errorf("output got %d bytes, want at most %d", len(output), bound)
+			}
+
+			decompressed, err := Decompress(output)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			assertEqualBytes(t, decompressed, tt.input)
+		})
+	}
+}
+
+func TestStoredBlockKeepsTable(t *testing.T) {
+	random := make([]byte, 6000)
+	rand.New(rand.NewSource(2)).Read(random)
+
+	var input bytes.Buffer
+	input.WriteString(strings.Repeat("abcabd", 1000))
+	input.Write(random)
+	input.WriteString(strings.Repeat("abcdab", 1000))
+
+	// the block after the stored one reuses the table stored before it
+	compressed := compressIndexed(t, input.Bytes(), WithAlphabet(AlphabetBytes), WithBlockSize(6000))
+
+	var types []blockType
+	r := bufio.NewReader(bytes.NewReader(compressed[headerSize:]))
+	for {
+		b, err := readBlock(r)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if b.typ == blockEnd {
+			break
+		}
+		types = append(types, b.typ)
+	}
+	assertEqual(t, fmt.Sprint(types), fmt.Sprint([]blockType{blockNewTable, blockStored, blockReuseTable}))
+
+	s, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	p := make([]byte, 7000)
+	if _, err := s.ReadAt(p, 5000); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, p, input.Bytes()[5000:12000])
+}
@@ -0,0 +1,117 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"compression_tool.nobletk/internal/bitio"
+	"compression_tool.nobletk/internal/bwt"
+)
+
+func init() {
+	RegisterCoder(MethodBWT, bwtCoder{})
+}
+
+// bwtModel holds the primary index of the transform and the table of the
+// zero run symbols, with the symbols of the block when encoding and as a
+// decode table when decoding.
+type bwtModel struct {
+	primary int
+	symbols []uint16
+	lengths map[rune]int
+	table   *decodeTable
+}
+
+// bwtCoder sorts data with the Burrows-Wheeler transform, turns the runs
+// it creates into zero runs with move-to-front and codes the zero run
+// symbols with a Huffman table.
+type bwtCoder struct{}
+
+func (bwtCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	out, primary := bwt.Transform(data)
+	symbols := bwt.EncodeZeroRuns(bwt.MoveToFront(out))
+
+	freqMap := make(FrequencyMap)
+	for _, s := range symbols {
+		freqMap[rune(s)]++
+	}
+	lengths, err := buildLengths(freqMap, cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bwtModel{primary: primary, symbols: symbols, lengths: lengths}, nil
+}
+
+// WriteModel stores the primary index, then the table.
+func (bwtCoder) WriteModel(m Model) []byte {
+	bm := m.(*bwtModel)
+	b := binary.AppendUvarint(nil, uint64(bm.primary))
+
+	return append(b, serializeLengths(bm.lengths)...)
+}
+
+// Encode codes the symbols built by BuildModel, data is not read again.
+func (bwtCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	bm := m.(*bwtModel)
+	var codes [bwt.NumSymbols]code
+	for char, c := range canonicalCodes(bm.lengths) {
+		codes[char] = c
+	}
+
+	var bitBuff bytes.Buffer
+	bw := bitio.NewBitWriter(&bitBuff)
+	for _, s := range bm.symbols {
+		bw.WriteBits(codes[s].bits, codes[s].length)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, err
+	}
+
+	return bitBuff.Bytes(), int(bw.BitsWritten()), nil
+}
+
+func (bwtCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	primary, n := binary.Uvarint(b)
+	if n <= 0 || primary > maxSectionSize {
+		return nil, errors.New("invalid primary index")
+	}
+
+	table, err := parseDecodeTable(b[n:], cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bwtModel{primary: int(primary), table: table}, nil
+}
+
+func (bwtCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	bm := m.(*bwtModel)
+	if bm.table == nil && totalBits != 0 {
+		return nil, errors.New("payload without code lengths")
+	}
+
+	r := bitio.NewBitReader(payload)
+	symbols := make([]uint16, 0, len(payload))
+	for r.BitsRead() < int64(totalBits) {
+		s, err := bm.table.readSymbol(r)
+		if err != nil {
+			return nil, err
+		}
+		if s >= bwt.NumSymbols {
+			return nil, errors.New("invalid zero run symbol")
+		}
+		symbols = append(symbols, uint16(s))
+	}
+	if r.BitsRead() > int64(totalBits) {
+		return nil, errors.New("last code exceeds payload bit count")
+	}
+
+	mtf, err := bwt.DecodeZeroRuns(symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	return bwt.Inverse(bwt.UndoMoveToFront(mtf), bm.primary)
+}
@@ -0,0 +1,63 @@
+package huff
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBWTLargeBlocks(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertRoundTrip(t, data, []Option{WithMethod(MethodBWT), WithBlockSize(900 << 10)})
+}
+
+func TestBWTBeatsLZ77(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lz, err := Compress(data, WithMethod(MethodLZ77))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	bwt, err := Compress(data, WithMethod(MethodBWT))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(bwt) >= len(lz) {
+		t.Errorf("BWT output got %d bytes, LZ77 output %d", len(bwt), len(lz))
+	}
+}
+
+func TestDecodeBWTInvalidBlock(t *testing.T) {
+	model, payload, totalBits := codeBlock(t, bwtCoder{}, []byte("banana bandana"), CoderConfig{})
+
+	tests := []struct {
+		name        string
+		model       []byte
+		payload     []byte
+		totalBits   int
+		expectedErr string
+	}{
+		{"No model", nil, payload, totalBits, "invalid primary index"},
+		{"No table", []byte{0}, []byte{0}, 1, "payload without code lengths"},
+		{"Bad primary index", append([]byte{100}, model[1:]...), payload, totalBits, "invalid primary index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := bwtCoder{}.ReadModel(tt.model, CoderConfig{})
+			if err == nil {
+				_, err = bwtCoder{}.Decode(m, tt.payload, tt.totalBits, CoderConfig{})
+			}
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Decode() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
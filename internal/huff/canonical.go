@@ -0,0 +1,206 @@
+package huff
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+// codeLengths returns the depth of every leaf in the tree, a leaf root is
+// given a length of one like in encodeTree.
+func codeLengths(root *huffmanNode) map[rune]int {
+	lengths := make(map[rune]int)
+	if root == nil {
+		return lengths
+	}
+	if root.Left == nil && root.Right == nil {
+		lengths[root.Char] = 1
+		return lengths
+	}
+
+	leafDepths(root, 0, lengths)
+
+	return lengths
+}
+
+func leafDepths(node *huffmanNode, depth int, lengths map[rune]int) {
+	if node == nil {
+		return
+	}
+
+	if node.Left == nil && node.Right == nil {
+		lengths[node.Char] = depth
+	}
+
+	leafDepths(node.Left, depth+1, lengths)
+	leafDepths(node.Right, depth+1, lengths)
+}
+
+// sortByLength orders symbols the way canonical codes are assigned: by code
+// length, then by symbol value.
+func sortByLength(lengths map[rune]int) []rune {
+	symbols := make([]rune, 0, len(lengths))
+	for char := range lengths {
+		symbols = append(symbols, char)
+	}
+
+	slices.SortFunc(symbols, func(a, b rune) int {
+		if c := cmp.Compare(lengths[a], lengths[b]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	return symbols
+}
+
+// canonicalCodes assigns canonical Huffman codes from code lengths alone, so
+// the decoder can rebuild the same table without the tree.
+func canonicalCodes(lengths map[rune]int) prefixTable {
+	preTab := make(prefixTable, len(lengths))
+
+	var bits uint64
+	prevLen := 0
+	for i, char := range sortByLength(lengths) {
+		length := lengths[char]
+		if i > 0 {
+			bits++
+		}
+		bits <<= length - prevLen
+		prevLen = length
+
+		preTab[char] = code{bits: bits, length: uint8(length)}
+	}
+
+	return preTab
+}
+
+// validateLengths checks the Kraft inequality so a corrupted header cannot
+// produce codes that are prefixes of each other. The sum stops as soon as
+// it is over the limit, before it can wrap around.
+func validateLengths(lengths map[rune]int) error {
+	const maxLen = maxCodeLength
+
+	var sum uint64
+	for _, length := range lengths {
+		if length < 1 || length > maxLen {
+			return errors.New("invalid code length")
+		}
+		sum += 1 << (maxLen - length)
+		if sum > 1<<maxLen {
+			return errors.New("code lengths are over-subscribed")
+		}
+	}
+
+	return nil
+}
+
+// A length byte above maxCodeLength repeats the previous length, like
+// code 16 of DEFLATE: lengthRepeat stands for minLengthRepeat copies and
+// every value above it for one more, up to maxLengthRepeat.
+const (
+	lengthRepeat    = maxCodeLength + 1
+	minLengthRepeat = 3
+	maxLengthRepeat = 0xFF - lengthRepeat + minLengthRepeat
+)
+
+// serializeLengths writes the code lengths ordered by symbol. Consecutive
+// symbols are grouped in runs stored as the gap from the end of the previous
+// run, the run size and the lengths of its symbols, where a length repeated
+// at least minLengthRepeat times is written once followed by a repeat byte.
+func serializeLengths(lengths map[rune]int) []byte {
+	symbols := make([]rune, 0, len(lengths))
+	for char := range lengths {
+		symbols = append(symbols, char)
+	}
+	slices.Sort(symbols)
+
+	var buff bytes.Buffer
+	next := rune(0)
+	prev := 0
+	for i := 0; i < len(symbols); {
+		j := i + 1
+		for j < len(symbols) && symbols[j] == symbols[j-1]+1 {
+			j++
+		}
+
+		buff.Write(binary.AppendUvarint(nil, uint64(symbols[i]-next)))
+		buff.Write(binary.AppendUvarint(nil, uint64(j-i)))
+		for k := i; k < j; {
+			length := lengths[symbols[k]]
+			repeat := 0
+			for k+repeat < j && repeat < maxLengthRepeat && lengths[symbols[k+repeat]] == prev {
+				repeat++
+			}
+			if repeat >= minLengthRepeat {
+				buff.WriteByte(byte(lengthRepeat + repeat - minLengthRepeat))
+				k += repeat
+				continue
+			}
+
+			buff.WriteByte(byte(length))
+			prev = length
+			k++
+		}
+
+		next = symbols[j-1] + 1
+		i = j
+	}
+
+	return buff.Bytes()
+}
+
+func deserializeLengths(b []byte) (map[rune]int, error) {
+	lengths := make(map[rune]int)
+
+	var next uint64
+	prev := 0
+	for len(b) > 0 {
+		gap, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("invalid symbol gap in code lengths")
+		}
+		b = b[n:]
+
+		count, n := binary.Uvarint(b)
+		if n <= 0 || count == 0 {
+			return nil, errors.New("invalid run size in code lengths")
+		}
+		b = b[n:]
+
+		next += gap
+		if next+count > 1<<31 {
+			return nil, errors.New("symbol out of range in code lengths")
+		}
+		for end := next + count; next < end; {
+			if len(b) == 0 {
+				return nil, errors.New("code lengths are truncated")
+			}
+			length := int(b[0])
+			b = b[1:]
+			if length < lengthRepeat {
+				lengths[rune(next)] = length
+				prev = length
+				next++
+				continue
+			}
+
+			repeat := uint64(length - lengthRepeat + minLengthRepeat)
+			if prev == 0 || repeat > end-next {
+				return nil, errors.New("invalid repeat in code lengths")
+			}
+			for ; repeat > 0; repeat-- {
+				lengths[rune(next)] = prev
+				next++
+			}
+		}
+	}
+
+	if err := validateLengths(lengths); err != nil {
+		return nil, err
+	}
+
+	return lengths, nil
+}
@@ -0,0 +1,107 @@
+package huff
+
+import (
+	"testing"
+)
+
+func TestCodeLengths(t *testing.T) {
+	root, _, err := buildTree(map[rune]int{' ': 3, 'a': 4, 'b': 3, 'c': 2, 'd': 1})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lengths := codeLengths(root)
+	expected := map[rune]int{' ': 2, 'a': 2, 'b': 2, 'c': 3, 'd': 3}
+
+	assertEqual(t, printSortedMap(lengths), printSortedMap(expected))
+}
+
+func TestCanonicalCodes(t *testing.T) {
+	lengths := map[rune]int{
+		'c': 4,
+		'd': 3,
+		'e': 1,
+		'k': 6,
+		'l': 3,
+		'm': 5,
+		'u': 3,
+		'z': 6,
+	}
+
+	expectedPrefix := map[rune]string{
+		'e': "0",
+		'd': "100",
+		'l': "101",
+		'u': "110",
+		'c': "1110",
+		'm': "11110",
+		'k': "111110",
+		'z': "111111",
+	}
+
+	actualPrefix := printPrefixTable(canonicalCodes(lengths))
+	assertEqual(t, actualPrefix, printPrefixTable(stringCodes(expectedPrefix)))
+}
+
+func TestSerializeLengths(t *testing.T) {
+	lengths := map[rune]int{' ': 2, 'a': 2, 'b': 3, 'c': 3, 'd': 3, 'ê': 4, '…': 4}
+
+	serialized := serializeLengths(lengths)
+	expected := []byte{32, 1, 2, 64, 4, 2, 3, 3, 3, 133, 1, 1, 4, 187, 62, 1, 4}
+	assertEqualBytes(t, serialized, expected)
+
+	actual, err := deserializeLengths(serialized)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, printSortedMap(actual), printSortedMap(lengths))
+}
+
+func TestSerializeRepeatedLengths(t *testing.T) {
+	lengths := make(map[rune]int)
+	for char := rune('a'); char < 'a'+8; char++ {
+		lengths[char] = 4
+	}
+	for char := rune(1000); char < 1000+300; char++ {
+		lengths[char] = 10
+	}
+	lengths[1300] = 9
+
+	serialized := serializeLengths(lengths)
+	expected := []byte{97, 8, 4, 68, 0xFF, 6, 0xAD, 2, 10, 255, 166, 9}
+	assertEqualBytes(t, serialized, expected)
+
+	actual, err := deserializeLengths(serialized)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, printSortedMap(actual), printSortedMap(lengths))
+}
+
+func TestDeserializeLengthsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Truncated gap", []byte{0x80}, "invalid symbol gap in code lengths"},
+		{"Empty run", []byte{97, 0}, "invalid run size in code lengths"},
+		{"Truncated run", []byte{97, 3, 1, 1}, "code lengths are truncated"},
+		{"Zero length", []byte{97, 2, 1, 0}, "invalid code length"},
+		{"Over-subscribed", []byte{97, 3, 1, 1, 1}, "code lengths are over-subscribed"},
+		{"Over-subscribed past the sum range", []byte{97, 4, 1, 1, 1, 1}, "code lengths are over-subscribed"},
+		{"Repeat without length", []byte{97, 3, 64}, "invalid repeat in code lengths"},
+		{"Repeat past the run", []byte{97, 3, 2, 64}, "invalid repeat in code lengths"},
+		{"Truncated repeat run", []byte{97, 5, 2, 64}, "code lengths are truncated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := deserializeLengths(tt.input)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("deserializeLengths() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package huff
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+)
+
+// Checksum selects the hash stored in the stream trailer, it is kept in the
+// low bits of the header flags.
+type Checksum byte
+
+const (
+	ChecksumCRC32 Checksum = iota
+	ChecksumCRC64
+	ChecksumSHA256
+)
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+func (c Checksum) newHash() (hash.Hash, error) {
+	switch c {
+	case ChecksumCRC32:
+		return crc32.NewIEEE(), nil
+	case ChecksumCRC64:
+		return crc64.New(crc64Table), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	}
+
+	return nil, fmt.Errorf("unknown checksum: %d", c)
+}
+
+// size returns the length of the hash in bytes.
+func (c Checksum) size() int {
+	switch c {
+	case ChecksumCRC32:
+		return crc32.Size
+	case ChecksumCRC64:
+		return crc64.Size
+	case ChecksumSHA256:
+		return sha256.Size
+	}
+
+	return 0
+}
+
+// digest hashes the original data and counts its length for the trailer
+// written after the last block.
+type digest struct {
+	hash   hash.Hash
+	length uint64
+}
+
+func newDigest(c Checksum) (*digest, error) {
+	h, err := c.newHash()
+	if err != nil {
+		return nil, err
+	}
+
+	return &digest{hash: h}, nil
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	d.length += uint64(len(p))
+	return d.hash.Write(p)
+}
+
+func (d *digest) trailer() []byte {
+	trailer := d.hash.Sum(nil)
+	return binary.BigEndian.AppendUint64(trailer, d.length)
+}
+
+func (d *digest) verify(trailer []byte) error {
+	size := d.hash.Size()
+	if len(trailer) != size+8 {
+		return fmt.Errorf("invalid trailer size: %d", len(trailer))
+	}
+
+	length := binary.BigEndian.Uint64(trailer[size:])
+	if length != d.length {
+		return fmt.Errorf("%w: length got %d, want %d", ErrChecksumMismatch, d.length, length)
+	}
+
+	if !bytes.Equal(d.hash.Sum(nil), trailer[:size]) {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package huff
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Model is what a Coder learns from the symbols of a block, the type is
+// up to the Coder.
+type Model any
+
+// CoderConfig holds the stream settings a Coder works with.
+type CoderConfig struct {
+	Alphabet Alphabet
+	// MaxCodeLen limits the code lengths of coders with prefix codes, zero
+	// leaves them unlimited.
+	MaxCodeLen int
+	// RunLength codes runs of a symbol with run symbols, only the Huffman
+	// coder supports it.
+	RunLength bool
+	// Window is how far back LZ77 matches reach, zero for the default. It
+	// is only set when encoding.
+	Window int
+}
+
+// Coder is an entropy coder the block container drives. Every block
+// stores the model written by WriteModel before its payload, the container
+// takes care of block framing, stored blocks, checksums and the index.
+type Coder interface {
+	// BuildModel counts the symbols of data and builds the model coding
+	// them.
+	BuildModel(data []byte, cfg CoderConfig) (Model, error)
+	// WriteModel serializes m for ReadModel.
+	WriteModel(m Model) []byte
+	// Encode codes data with m and returns the payload and its size in
+	// bits.
+	Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error)
+	// ReadModel parses a model written by WriteModel.
+	ReadModel(b []byte, cfg CoderConfig) (Model, error)
+	// Decode decodes a payload of totalBits bits coded with m.
+	Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error)
+}
+
+// coders holds the registered coders by method.
+var coders = make(map[Method]Coder)
+
+// RegisterCoder makes c code the blocks of method m. The blocks of every
+// coder but MethodHuffman's are stored as coded blocks. It panics when m
+// is taken, so it is meant to be called from init.
+func RegisterCoder(m Method, c Coder) {
+	if _, ok := coders[m]; ok {
+		panic(fmt.Sprintf("huff: method %d is taken", m))
+	}
+	coders[m] = c
+}
+
+func coderFor(m Method) (Coder, error) {
+	c, ok := coders[m]
+	if !ok {
+		return nil, fmt.Errorf("no coder for method %d", m)
+	}
+
+	return c, nil
+}
+
+func init() {
+	RegisterCoder(MethodHuffman, huffmanCoder{})
+}
+
+// huffmanModel holds what encoding needs, the symbol frequencies, code
+// lengths and codes, or what decoding needs, the decode table.
+type huffmanModel struct {
+	freqMap FrequencyMap
+	lengths map[rune]int
+	codes   prefixTable
+	table   *decodeTable
+}
+
+// huffmanCoder codes blocks with a static table of canonical Huffman codes.
+type huffmanCoder struct{}
+
+func (huffmanCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	var freqMap FrequencyMap
+	var err error
+	if cfg.RunLength {
+		symbols, err := runSymbols(data, cfg.Alphabet)
+		if err != nil {
+			return nil, err
+		}
+		freqMap = symbolsFrequency(symbols)
+	} else if freqMap, err = getSymbolsFrequency(data, cfg.Alphabet); err != nil {
+		return nil, err
+	}
+
+	lengths, err := buildLengths(freqMap, cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &huffmanModel{freqMap: freqMap, lengths: lengths, codes: canonicalCodes(lengths)}, nil
+}
+
+func (huffmanCoder) WriteModel(m Model) []byte {
+	return serializeLengths(m.(*huffmanModel).lengths)
+}
+
+func (huffmanCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	codes := m.(*huffmanModel).codes
+	if !cfg.RunLength {
+		bitBuff, totalBits, err := encData(data, codes, cfg.Alphabet)
+		return bitBuff.Bytes(), totalBits, err
+	}
+
+	symbols, err := runSymbols(data, cfg.Alphabet)
+	if err != nil {
+		return nil, 0, err
+	}
+	bitBuff, totalBits, err := encSymbols(symbols, codes)
+
+	return bitBuff.Bytes(), totalBits, err
+}
+
+func (huffmanCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	lengths, err := deserializeLengths(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMaxLength(lengths, cfg.MaxCodeLen); err != nil {
+		return nil, err
+	}
+
+	table, err := newDecodeTable(canonicalCodes(lengths))
+	if err != nil {
+		return nil, err
+	}
+
+	return &huffmanModel{lengths: lengths, table: table}, nil
+}
+
+func (huffmanCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	hm := m.(*huffmanModel)
+	if len(hm.lengths) == 0 && totalBits != 0 {
+		return nil, errors.New("payload without code lengths")
+	}
+	if cfg.RunLength {
+		return decodeRuns(payload, hm.table, totalBits, cfg.Alphabet)
+	}
+
+	return decode(payload, hm.table, totalBits, cfg.Alphabet)
+}
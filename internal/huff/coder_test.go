@@ -0,0 +1,173 @@
+package huff
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"compression_tool.nobletk/internal/bitio"
+)
+
+// methodASCII is the method of asciiCoder, a number no built-in method
+// uses.
+const methodASCII Method = 200
+
+func init() {
+	RegisterCoder(methodASCII, asciiCoder{})
+}
+
+// asciiCoder codes ASCII bytes with 7 bits each and needs no model.
+type asciiCoder struct{}
+
+func (asciiCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) { return nil, nil }
+
+func (asciiCoder) WriteModel(m Model) []byte { return nil }
+
+func (asciiCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	var buff bytes.Buffer
+	bw := bitio.NewBitWriter(&buff)
+	for _, c := range data {
+		if c >= 0x80 {
+			return nil, 0, errors.New("byte is not ascii")
+		}
+		bw.WriteBits(uint64(c), 7)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, err
+	}
+
+	return buff.Bytes(), int(bw.BitsWritten()), nil
+}
+
+func (asciiCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) { return nil, nil }
+
+func (asciiCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	if totalBits%7 != 0 || totalBits > 8*len(payload) {
+		return nil, errors.New("invalid payload bit count")
+	}
+
+	r := bitio.NewBitReader(payload)
+	decompressed := make([]byte, 0, totalBits/7)
+	for i := 0; i < totalBits/7; i++ {
+		decompressed = append(decompressed, byte(r.ReadBits(7)))
+	}
+
+	return decompressed, nil
+}
+
+// TestCodersRoundTrip runs every method, and the Huffman options with a
+// format of their own, over the same inputs. Cases only one method cares
+// about are in the test file of the method.
+func TestCodersRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	dict, err := TrainDictionary(0, [][]byte{data[:20000]}, 12)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	methods := []struct {
+		name       string
+		opts       []Option
+		decodeOpts []Option
+		// limited tells whether the method takes WithMaxCodeLength.
+		limited bool
+	}{
+		{name: "Huffman", limited: true},
+		{name: "Run length", opts: []Option{WithRunLength()}, limited: true},
+		{name: "Dictionary", opts: []Option{WithDictionary(dict)}, decodeOpts: []Option{WithDictionary(dict)}, limited: true},
+		{name: "Adaptive", opts: []Option{WithMethod(MethodAdaptive)}},
+		{name: "LZ77", opts: []Option{WithMethod(MethodLZ77)}, limited: true},
+		{name: "BWT", opts: []Option{WithMethod(MethodBWT)}, limited: true},
+		{name: "Context", opts: []Option{WithMethod(MethodContext)}, limited: true},
+		{name: "Range", opts: []Option{WithMethod(MethodRange)}},
+		{name: "ANS", opts: []Option{WithMethod(MethodANS)}},
+		{name: "Words", opts: []Option{WithMethod(MethodWords)}, limited: true},
+	}
+
+	inputs := []struct {
+		name  string
+		input []byte
+		opts  []Option
+		// limits tells whether opts hold WithMaxCodeLength.
+		limits bool
+	}{
+		{name: "Empty", input: nil},
+		{name: "Single symbol", input: []byte("aaaaaaaa")},
+		{name: "Single rune", input: []byte("é")},
+		{name: "Runes", input: []byte("ééé abc 世界 aaa")},
+		{name: "Long run", input: bytes.Repeat([]byte{'x'}, 100000)},
+		{name: "Bytes", input: bytes.Repeat([]byte{0, 1, 2, 0xfe, 0xff, ' '}, 10000), opts: []Option{WithAlphabet(AlphabetBytes)}},
+		{name: "Small blocks", input: data[:100000], opts: []Option{WithBlockSize(1000)}},
+		{name: "Limited codes", input: data[:200000], opts: []Option{WithMaxCodeLength(12)}, limits: true},
+		{name: "Test file", input: data, opts: []Option{WithConcurrency(4), WithIndex()}},
+	}
+
+	for _, m := range methods {
+		for _, in := range inputs {
+			if in.limits && !m.limited {
+				continue
+			}
+			t.Run(m.name+"/"+in.name, func(t *testing.T) {
+				opts := append(append([]Option(nil), m.opts...), in.opts...)
+				decodeOpts := append([]Option{WithConcurrency(2)}, m.decodeOpts...)
+				assertRoundTrip(t, in.input, opts, decodeOpts...)
+			})
+		}
+	}
+}
+
+func TestRegisteredCoderRoundTrip(t *testing.T) {
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 200))
+	opts := []Option{WithMethod(methodASCII), WithBlockSize(1024), WithIndex()}
+
+	compressed, err := Compress(input, opts...)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(compressed) >= len(input) {
+		t.Errorf("compressed size got %d, want less than %d", len(compressed), len(input))
+	}
+
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, input)
+
+	s, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	part := make([]byte, 100)
+	if _, err := s.ReadAt(part, 2000); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, part, input[2000:2100])
+}
+
+func TestRegisterCoderPanics(t *testing.T) {
+	tests := []struct {
+		name   string
+		method Method
+	}{
+		{"Registered method", MethodRange},
+		{"Test method", methodASCII},
+		{"LZ77 method", MethodLZ77},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterCoder(%d) did not panic", tt.method)
+				}
+			}()
+			RegisterCoder(tt.method, asciiCoder{})
+		})
+	}
+}
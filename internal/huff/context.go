@@ -0,0 +1,275 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"compression_tool.nobletk/internal/bitio"
+)
+
+// contextMinCount is the fewest symbols a context needs to be considered
+// for a table of its own, rarer contexts always use the shared table.
+const contextMinCount = 64
+
+func init() {
+	RegisterCoder(MethodContext, contextCoder{})
+}
+
+// contextModel holds the shared table and the tables of the contexts that
+// have their own, as code lengths when encoding and as decode tables when
+// decoding.
+type contextModel struct {
+	shared      map[rune]int
+	own         map[rune]map[rune]int
+	sharedTable *decodeTable
+	tables      map[rune]*decodeTable
+}
+
+// contextCoder codes every symbol with the table of the symbol before it.
+type contextCoder struct{}
+
+// BuildModel gives a context its own table only when the table and the
+// payload coded with it are smaller than the payload coded with an
+// order-0 table of the block. The first symbol and the symbols of the
+// other contexts use the shared table, which is built from those symbols
+// alone.
+func (contextCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	symbols, err := splitSymbols(data, cfg.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(FrequencyMap)
+	contexts := make(map[rune]FrequencyMap)
+	totals := make(map[rune]int)
+	for i, char := range symbols {
+		all[char]++
+		if i == 0 {
+			continue
+		}
+		ctx := symbols[i-1]
+		if contexts[ctx] == nil {
+			contexts[ctx] = make(FrequencyMap)
+		}
+		contexts[ctx][char]++
+		totals[ctx]++
+	}
+
+	allLengths, err := buildLengths(all, cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	own := make(map[rune]map[rune]int)
+	for ctx, freqMap := range contexts {
+		if totals[ctx] < contextMinCount {
+			continue
+		}
+		lengths, err := buildLengths(freqMap, cfg.MaxCodeLen)
+		if err != nil {
+			return nil, err
+		}
+
+		ownBits, _ := codedSize(freqMap, lengths)
+		ownBits += 8 * (len(serializeLengths(lengths)) + 2*uvarintLen(int(ctx)))
+		if sharedBits, _ := codedSize(freqMap, allLengths); ownBits < sharedBits {
+			own[ctx] = lengths
+		}
+	}
+
+	shared := make(FrequencyMap)
+	for i, char := range symbols {
+		if i == 0 || own[symbols[i-1]] == nil {
+			shared[char]++
+		}
+	}
+	sharedLengths, err := buildLengths(shared, cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contextModel{shared: sharedLengths, own: own}, nil
+}
+
+// WriteModel stores the shared table after its length, then the context
+// tables.
+func (contextCoder) WriteModel(m Model) []byte {
+	cm := m.(*contextModel)
+	table := serializeLengths(cm.shared)
+
+	b := binary.AppendUvarint(nil, uint64(len(table)))
+	b = append(b, table...)
+
+	return append(b, serializeContexts(cm.own)...)
+}
+
+func (contextCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	cm := m.(*contextModel)
+	symbols, err := splitSymbols(data, cfg.Alphabet)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sharedCodes := canonicalCodes(cm.shared)
+	ctxCodes := make(map[rune]prefixTable, len(cm.own))
+	for ctx, lengths := range cm.own {
+		ctxCodes[ctx] = canonicalCodes(lengths)
+	}
+
+	var bitBuff bytes.Buffer
+	bw := bitio.NewBitWriter(&bitBuff)
+	for i, char := range symbols {
+		preTab := sharedCodes
+		if i > 0 {
+			if codes, ok := ctxCodes[symbols[i-1]]; ok {
+				preTab = codes
+			}
+		}
+		c := preTab[char]
+		if c.length == 0 {
+			return nil, 0, errors.New("char not found in prefix table")
+		}
+		bw.WriteBits(c.bits, c.length)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, err
+	}
+
+	return bitBuff.Bytes(), int(bw.BitsWritten()), nil
+}
+
+func (contextCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	tableLen, n := binary.Uvarint(b)
+	if n <= 0 || tableLen > uint64(len(b)-n) {
+		return nil, errors.New("invalid shared table length")
+	}
+	b = b[n:]
+
+	shared, err := parseDecodeTable(b[:tableLen], cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := parseContexts(b[tableLen:], cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contextModel{sharedTable: shared, tables: tables}, nil
+}
+
+func (contextCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	cm := m.(*contextModel)
+
+	decompressed := make([]byte, 0, len(payload)*2)
+	r := bitio.NewBitReader(payload)
+	prev := rune(-1)
+	for r.BitsRead() < int64(totalBits) {
+		table, ok := cm.tables[prev]
+		if !ok {
+			table = cm.sharedTable
+		}
+		if table == nil {
+			return nil, errors.New("payload without code lengths")
+		}
+
+		char, err := table.readSymbol(r)
+		if err != nil {
+			return nil, err
+		}
+		decompressed = cfg.Alphabet.appendSymbol(decompressed, char)
+		prev = char
+	}
+	if r.BitsRead() > int64(totalBits) {
+		return nil, errors.New("last code exceeds payload bit count")
+	}
+
+	return decompressed, nil
+}
+
+// splitSymbols returns the symbols of data.
+func splitSymbols(data []byte, alphabet Alphabet) ([]rune, error) {
+	symbols := make([]rune, 0, len(data))
+	for i := 0; i < len(data); {
+		char, sz, err := alphabet.next(data[i:])
+		if err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, char)
+		i += sz
+	}
+
+	return symbols, nil
+}
+
+// serializeContexts writes the number of context tables, then for every
+// context in ascending order its gap from the previous context plus one,
+// the table length and the table.
+func serializeContexts(tables map[rune]map[rune]int) []byte {
+	contexts := make([]rune, 0, len(tables))
+	for ctx := range tables {
+		contexts = append(contexts, ctx)
+	}
+	slices.Sort(contexts)
+
+	buff := binary.AppendUvarint(nil, uint64(len(contexts)))
+	next := rune(0)
+	for _, ctx := range contexts {
+		table := serializeLengths(tables[ctx])
+		buff = binary.AppendUvarint(buff, uint64(ctx-next))
+		buff = binary.AppendUvarint(buff, uint64(len(table)))
+		buff = append(buff, table...)
+		next = ctx + 1
+	}
+
+	return buff
+}
+
+// parseContexts builds the decode tables of serialized context tables.
+func parseContexts(b []byte, maxCodeLen int) (map[rune]*decodeTable, error) {
+	r := bytes.NewReader(b)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.New("invalid context table count")
+	}
+	if count > uint64(len(b)) {
+		return nil, fmt.Errorf("context table count is too large: %d", count)
+	}
+
+	tables := make(map[rune]*decodeTable, count)
+	next := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		gap, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid context of table %d", i)
+		}
+		next += gap
+		if next >= 1<<31 {
+			return nil, fmt.Errorf("context out of range in table %d", i)
+		}
+
+		tableLen, err := binary.ReadUvarint(r)
+		if err != nil || tableLen > uint64(r.Len()) {
+			return nil, fmt.Errorf("context table %d is truncated", i)
+		}
+		table := make([]byte, tableLen)
+		r.Read(table)
+
+		t, err := parseDecodeTable(table, maxCodeLen)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, fmt.Errorf("context table %d is empty", i)
+		}
+		tables[rune(next)] = t
+		next++
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("trailing data after context tables")
+	}
+
+	return tables, nil
+}
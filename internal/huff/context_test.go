@@ -0,0 +1,71 @@
+package huff
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestContextBeatsOrder0(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	order0, err := Compress(data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	order1, err := Compress(data, WithMethod(MethodContext))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(order1) >= len(order0)*9/10 {
+		t.Errorf("context output got %d bytes, want less than 90%% of %d", len(order1), len(order0))
+	}
+}
+
+func TestContextRareContextsShareTable(t *testing.T) {
+	m, err := contextCoder{}.BuildModel([]byte("the quick brown fox jumps over the lazy dog"), CoderConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, len(m.(*contextModel).own), 0)
+
+	input := []byte(strings.Repeat("qu", 300) + strings.Repeat("ab", 300) + "xyz")
+	model, _, _ := codeBlock(t, contextCoder{}, input, CoderConfig{})
+	m, err = contextCoder{}.ReadModel(model, CoderConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tables := m.(*contextModel).tables
+	assertEqual(t, len(tables), 4)
+	if tables['q'] == nil || tables['b'] == nil || tables['x'] != nil {
+		t.Errorf("context tables got %v, want tables for q, u, a and b", tables)
+	}
+}
+
+func TestParseContextsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Missing count", nil, "invalid context table count"},
+		{"Count too large", []byte{5}, "context table count is too large: 5"},
+		{"Missing context", []byte{1}, "invalid context of table 0"},
+		{"Truncated table", []byte{1, 'a', 3, 0}, "context table 0 is truncated"},
+		{"Empty table", []byte{1, 'a', 0}, "context table 0 is empty"},
+		{"Trailing data", []byte{0, 1}, "trailing data after context tables"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseContexts(tt.input, 0)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("parseContexts() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -3,10 +3,39 @@ package huff
 import (
 	"bytes"
 	"errors"
+	"io"
 	"unicode/utf8"
 )
 
-func Decompress(data []byte) ([]byte, error) {
+// Decompress decodes data, streams concatenated after the first one are
+// decoded as well. With WithConcurrency, a single stream written with
+// WithIndex is split between the goroutines at the block offsets of its
+// index, other streams are decoded in batches by a Reader.
+func Decompress(data []byte, opts ...Option) ([]byte, error) {
+	o := newOptions(opts)
+	if o.legacy {
+		return decompressLegacy(data)
+	}
+	if o.concurrency > 1 && o.validate() == nil {
+		if decompressed, ok, err := decompressIndexed(data, o); ok {
+			return decompressed, err
+		}
+	}
+
+	zr, err := NewReader(bytes.NewReader(data), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	return decompressed, nil
+}
+
+func decompressLegacy(data []byte) ([]byte, error) {
 	if len(data) < 2 {
 		return nil, errors.New("data is too short")
 	}
@@ -31,12 +60,16 @@ func Decompress(data []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	prefix := make(map[rune]string, 0)
-	encodeTree(treeRoot, "", prefix)
+	prefix := make(prefixTable, 0)
+	encodeTree(treeRoot, code{}, prefix)
 
-	lookup := getEncLookup(prefix)
+	table, err := newDecodeTable(prefix)
+	if err != nil {
+		return nil, err
+	}
 
-	decompressed, err := decode(encData, lookup, bitLen)
+	totalBits := (len(encData)-1)*8 + bitLen
+	decompressed, err := decode(encData, table, totalBits, AlphabetRunes)
 	if err != nil {
 		return nil, err
 	}
@@ -82,60 +115,11 @@ func getEncData(b []byte) ([]byte, error) {
 	return b[startIdx:], nil
 }
 
-type byteLookup struct {
-	code []byte
-	char rune
-}
-
-func getEncLookup(pt map[rune]string) []byteLookup {
-	lookup := make([]byteLookup, 0, len(pt))
-
-	for char, code := range pt {
-		bl := byteLookup{
-			code: []byte(code),
-			char: char,
-		}
-		lookup = append(lookup, bl)
-	}
-
-	return lookup
-}
-
-func decode(enc []byte, lookup []byteLookup, validBits int) ([]byte, error) {
-	decompressed := make([]byte, 0)
-	currentBits := make([]byte, 0)
-	totalBits := (len(enc)-1)*8 + validBits
-
-	bitIndex := 0
-
-	for i := 0; i < len(enc); i++ {
-		for bit := 7; bit >= 0; bit-- {
-			if bitIndex >= totalBits {
-				break
-			}
-
-			currentBit := ((enc[i] >> bit) & 1) + '0'
-			currentBits = append(currentBits, byte(currentBit))
-
-			for _, entry := range lookup {
-				if byteSlicesEqual(currentBits, entry.code) {
-					decompressed = utf8.AppendRune(decompressed, entry.char)
-					currentBits = make([]byte, 0)
-					break
-				}
-			}
-			bitIndex++
-		}
-	}
-
-	return decompressed, nil
-}
-
 func newInternalNode() *huffmanNode {
 	return &huffmanNode{
 		Char:  0,
 		Count: 0,
-		Code:  "",
+		Code:  code{},
 		Left:  nil,
 		Right: nil,
 	}
@@ -145,7 +129,7 @@ func newLeafNode(char rune) *huffmanNode {
 	return &huffmanNode{
 		Char:  char,
 		Count: 0,
-		Code:  "",
+		Code:  code{},
 		Left:  nil,
 		Right: nil,
 	}
@@ -1,12 +1,19 @@
 package huff
 
 import (
+	"errors"
+	"os"
+	"strings"
 	"testing"
 	"unicode/utf8"
 )
 
 func TestDecompress(t *testing.T) {
-	input := []byte{5, 31, 0, 0, 0, 1, 100, 1, 99, 1, 32, 0, 1, 98, 1, 97, 37, 37, 255, 106, 73, 64}
+	input := []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 0, 0,
+		1, 9, 32, 1, 2, 64, 4, 2, 2, 3, 3,
+		29, 85, 42, 54, 56,
+		0,
+		244, 43, 22, 3, 0, 0, 0, 0, 0, 0, 0, 13}
 
 	decompressed, err := Decompress(input)
 	if err != nil {
@@ -19,6 +26,87 @@ func TestDecompress(t *testing.T) {
 	assertEqual(t, actualDecompText, expectedDecompText)
 }
 
+func TestDecompressLegacy(t *testing.T) {
+	input := []byte{5, 31, 0, 0, 0, 1, 100, 1, 99, 1, 32, 0, 1, 98, 1, 97, 37, 37, 255, 106, 73, 64}
+
+	decompressed, err := Decompress(input, WithLegacyFormat())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	assertEqual(t, string(decompressed), `aaaa bbb cc d`)
+}
+
+func TestDecompressInvalidHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Short header", []byte{'H', 'U', 'F'}, "data is too short"},
+		{"Legacy without option", []byte{5, 31, 0, 0, 0, 1, 100, 1, 99, 1, 32, 0}, "invalid magic number"},
+		{"Unknown version", []byte{'H', 'U', 'F', 0x1A, 9, 0, 0, 0, 0}, "unsupported format version: 9"},
+		{"Unknown method", []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 255, 0}, "unknown method: 255"},
+		{"Unknown checksum", []byte{'H', 'U', 'F', 0x1A, 3, 0x03, 0, 0, 0}, "unknown checksum: 3"},
+		{"Unknown alphabet", []byte{'H', 'U', 'F', 0x1A, 3, 0x0C, 0, 0, 0}, "unknown alphabet: 3"},
+		{"Unknown flags", []byte{'H', 'U', 'F', 0x1A, 3, 0x20, 0, 0, 0}, "unknown header flags: 0x20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decompress(tt.input)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Decompress() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestDecompressCorrupted(t *testing.T) {
+	compressed, err := Compress([]byte("aaaa bbb cc d"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	payloadIdx := headerSize + 1 + 1 + 9 + 1
+	trailerIdx := payloadIdx + 4 + 1
+
+	tests := []struct {
+		name        string
+		corrupt     func(b []byte) []byte
+		expectedErr string
+	}{
+		{"Payload bit flip", func(b []byte) []byte { b[payloadIdx] ^= 0x01; return b }, "checksum mismatch"},
+		{"Checksum bit flip", func(b []byte) []byte { b[trailerIdx] ^= 0x80; return b }, "checksum mismatch"},
+		{"Length changed", func(b []byte) []byte { b[len(b)-1] = 12; return b }, "checksum mismatch: length got 13, want 12"},
+		{"Truncated trailer", func(b []byte) []byte { return b[:len(b)-1] }, "truncated trailer: needs 12 bytes, 11 left"},
+		{"Trailing byte", func(b []byte) []byte { return append(b, 0) }, "data is too short"},
+		{"Trailing garbage", func(b []byte) []byte { return append(b, "garbage data"...) }, "invalid magic number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.corrupt(append([]byte(nil), compressed...))
+
+			_, err := Decompress(input)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Decompress() error got=%v, want=%v", err, tt.expectedErr)
+			}
+			if strings.HasPrefix(tt.expectedErr, "checksum") && !errors.Is(err, ErrChecksumMismatch) {
+				t.Errorf("Decompress() error %v is not ErrChecksumMismatch", err)
+			}
+		})
+	}
+}
+
+func TestDecompressPayloadWithoutTable(t *testing.T) {
+	input := []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 0, 0, 1, 0, 8, 0xff}
+
+	_, err := Decompress(input)
+	if err == nil || err.Error() != "payload without code lengths" {
+		t.Errorf("Decompress() error got=%v, want=%v", err, "payload without code lengths")
+	}
+}
+
 func TestGetEncLookup(t *testing.T) {
 	input := map[rune]string{
 		' ': "01",
@@ -28,7 +116,7 @@ func TestGetEncLookup(t *testing.T) {
 		'…': "000",
 	}
 
-	lookup := getEncLookup(input)
+	lookup := getEncLookup(stringCodes(input))
 	actualSorted := printLookup(lookup)
 
 	expectedLookup := []byteLookup{
@@ -44,6 +132,104 @@ func TestGetEncLookup(t *testing.T) {
 }
 
 func TestDecode(t *testing.T) {
+	tests := []struct {
+		enc         []byte
+		bits        int
+		prefix      prefixTable
+		expectedDec []byte
+	}{
+		{
+			enc:  []byte{255, 106, 73, 64},
+			bits: 29,
+			prefix: stringCodes(map[rune]string{
+				'…': "000",
+				'c': "001",
+				' ': "01",
+				'b': "10",
+				'a': "11",
+			}),
+
+			expectedDec: utf8.AppendRune([]byte{
+				'a', 'a', 'a', 'a', ' ',
+				'b', 'b', 'b', ' ',
+				'c', 'c', ' ',
+			}, '…'),
+		},
+		{
+			enc:         []byte{0x00},
+			bits:        3,
+			prefix:      stringCodes(map[rune]string{'z': "0"}),
+			expectedDec: []byte("zzz"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Helper()
+
+		table, err := newDecodeTable(tt.prefix)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		actualDecode, err := decode(tt.enc, table, tt.bits, AlphabetRunes)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		assertEqualBytes(t, actualDecode, tt.expectedDec)
+	}
+}
+
+func TestDecodeLongCodes(t *testing.T) {
+	freqMap := fibonacciFrequencies(30)
+	root, _, err := buildTree(freqMap)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	prefix := canonicalCodes(codeLengths(root))
+
+	var input []byte
+	for _, char := range sortRunes(prefix) {
+		input = utf8.AppendRune(input, char)
+	}
+
+	enc, totalBits, err := encData(input, prefix, AlphabetRunes)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	table, err := newDecodeTable(prefix)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if table.entries[len(table.entries)-1].next == nil {
+		t.Fatal("expected a sub-table for codes longer than the root table")
+	}
+
+	actualDecode, err := decode(enc.Bytes(), table, totalBits, AlphabetRunes)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, actualDecode, input)
+}
+
+func TestDecodeInvalidPayload(t *testing.T) {
+	table, err := newDecodeTable(stringCodes(map[rune]string{'a': "0", 'b': "10"}))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = decode([]byte{0xc0}, table, 2, AlphabetRunes)
+	if err == nil || err.Error() != "invalid code in payload" {
+		t.Errorf("decode() error got=%v, want=%v", err, "invalid code in payload")
+	}
+
+	_, err = decode([]byte{0x80}, table, 1, AlphabetRunes)
+	if err == nil || err.Error() != "last code exceeds payload bit count" {
+		t.Errorf("decode() error got=%v, want=%v", err, "last code exceeds payload bit count")
+	}
+}
+
+func TestDecodeLinear(t *testing.T) {
 	tests := []struct {
 		enc         []byte
 		bits        int
@@ -52,7 +238,7 @@ func TestDecode(t *testing.T) {
 	}{
 		{
 			enc:  []byte{255, 106, 73, 64},
-			bits: 5,
+			bits: 29,
 			lookup: []byteLookup{
 				{code: []byte{48, 48, 48}, char: '…'}, // "000" -> '…'
 				{code: []byte{48, 48, 49}, char: 'c'}, // "001" -> 'c'
@@ -72,7 +258,7 @@ func TestDecode(t *testing.T) {
 	for _, tt := range tests {
 		t.Helper()
 
-		actualDecode, err := decode(tt.enc, tt.lookup, tt.bits)
+		actualDecode, err := decodeLinear(tt.enc, tt.lookup, tt.bits, AlphabetRunes)
 		if err != nil {
 			t.Fatal(err.Error())
 		}
@@ -105,7 +291,7 @@ func TestRebuildEncTree(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 	prefixTable := make(prefixTable, 0)
-	encodeTree(root, "", prefixTable)
+	encodeTree(root, code{}, prefixTable)
 	// printTree(root, 0)
 
 	expectedPrefix := map[rune]string{
@@ -125,7 +311,7 @@ func TestRebuildEncTree(t *testing.T) {
 	}
 
 	actualPrefix := printPrefixTable(prefixTable)
-	expectedSortedPrefix := printPrefixTable(expectedPrefix)
+	expectedSortedPrefix := printPrefixTable(stringCodes(expectedPrefix))
 	assertEqual(t, actualPrefix, expectedSortedPrefix)
 }
 
@@ -161,10 +347,66 @@ func TestDecompressInvalidInput(t *testing.T) {
 	for _, tt := range tests {
 		t.Helper()
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := Decompress(tt.input)
+			_, err := Decompress(tt.input, WithLegacyFormat())
 			if err == nil || err.Error() != tt.expectedErr {
 				t.Errorf("Decompress() error got=%v, want=%v", err, tt.expectedErr)
 			}
 		})
 	}
 }
+
+// benchmarkPayload encodes testdata/test.txt with canonical codes.
+func benchmarkPayload(b *testing.B) ([]byte, prefixTable, []byte, int) {
+	b.Helper()
+
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+	freqMap, err := getRunesFrequency(data)
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+	lengths, err := buildLengths(freqMap, 0)
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+	prefix := canonicalCodes(lengths)
+	enc, totalBits, err := encData(data, prefix, AlphabetRunes)
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+
+	return data, prefix, enc.Bytes(), totalBits
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data, prefix, enc, totalBits := benchmarkPayload(b)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		table, err := newDecodeTable(prefix)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+		if _, err := decode(enc, table, totalBits, AlphabetRunes); err != nil {
+			b.Fatal(err.Error())
+		}
+	}
+}
+
+func BenchmarkDecodeLinear(b *testing.B) {
+	data, prefix, enc, totalBits := benchmarkPayload(b)
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeLinear(enc, getEncLookup(prefix), totalBits, AlphabetRunes); err != nil {
+			b.Fatal(err.Error())
+		}
+	}
+}
@@ -0,0 +1,491 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math/bits"
+
+	"compression_tool.nobletk/internal/bitio"
+	"compression_tool.nobletk/internal/lz77"
+)
+
+const (
+	// deflateMaxCodeLen is the longest literal, length or distance code
+	// DEFLATE allows, code length codes are limited to 7 bits.
+	deflateMaxCodeLen = 15
+	codeLenMaxCodeLen = 7
+	deflateWindow     = 32 << 10
+	// maxStoredLen is the most a stored DEFLATE block can hold.
+	maxStoredLen = 1<<16 - 1
+
+	endOfBlock      rune = 256
+	numLitLenCodes       = 288
+	numDistCodes         = 30
+	numCodeLenCodes      = 19
+)
+
+// DEFLATE block types, as stored in the BTYPE field.
+const (
+	deflateStored = iota
+	deflateFixed
+	deflateDynamic
+)
+
+// codeLengthOrder is the order code length code lengths are sent in.
+var codeLengthOrder = [numCodeLenCodes]int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+var gzipHeader = []byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 255}
+
+// fixedLitLengths and fixedDistLengths are the code lengths of the fixed
+// Huffman block type.
+var fixedLitLengths, fixedDistLengths = func() (map[rune]int, map[rune]int) {
+	lit := make(map[rune]int, numLitLenCodes)
+	for i := rune(0); i < numLitLenCodes; i++ {
+		switch {
+		case i < 144:
+			lit[i] = 8
+		case i < 256:
+			lit[i] = 9
+		case i < 280:
+			lit[i] = 7
+		default:
+			lit[i] = 8
+		}
+	}
+
+	dist := make(map[rune]int, 32)
+	for i := rune(0); i < 32; i++ {
+		dist[i] = 5
+	}
+
+	return lit, dist
+}()
+
+// deflateCodes holds the codes of one block with their bits reversed:
+// DEFLATE sends a code starting from its first bit but packs bits from the
+// lowest one.
+type deflateCodes struct {
+	lit  [numLitLenCodes]code
+	dist [32]code
+}
+
+func newDeflateCodes(litLengths, distLengths map[rune]int) *deflateCodes {
+	c := &deflateCodes{}
+	for char, cd := range canonicalCodes(litLengths) {
+		c.lit[char] = reverseCode(cd)
+	}
+	for char, cd := range canonicalCodes(distLengths) {
+		c.dist[char] = reverseCode(cd)
+	}
+
+	return c
+}
+
+func reverseCode(c code) code {
+	return code{bits: bits.Reverse64(c.bits) >> (64 - c.length), length: c.length}
+}
+
+var fixedCodes = newDeflateCodes(fixedLitLengths, fixedDistLengths)
+
+// codeLenSymbol is a symbol of the code length alphabet with its extra
+// bits: 0 to 15 are lengths, 16 repeats the previous length, 17 and 18
+// are runs of zeros.
+type codeLenSymbol struct {
+	sym   rune
+	n     uint8
+	extra uint64
+}
+
+// deflatePlan is one DEFLATE block ready to be written. Planning is the
+// expensive part and runs concurrently, writing must follow the stream
+// order since blocks are not byte aligned.
+type deflatePlan struct {
+	data   []byte
+	tokens []lz77.Token
+	typ    int
+	codes  *deflateCodes
+	// the dynamic block header
+	numLit, numDist, numCodeLen int
+	codeLenCodes                *deflateCodes
+	codeLenOrder                [numCodeLenCodes]int
+	codeLenSymbols              []codeLenSymbol
+}
+
+// planDeflate parses data and picks whichever of a stored, fixed or
+// dynamic block is the smallest.
+func planDeflate(data []byte) (*deflatePlan, error) {
+	p := &deflatePlan{data: data, typ: deflateFixed, codes: fixedCodes}
+	if len(data) == 0 {
+		return p, nil
+	}
+	p.tokens = lz77.Parse(data, deflateWindow)
+
+	litFreq := FrequencyMap{endOfBlock: 1}
+	distFreq := make(FrequencyMap)
+	for _, t := range p.tokens {
+		if t.Length == 0 {
+			litFreq[rune(t.Literal)]++
+			continue
+		}
+		sym, _, _ := lengthSymbol(t.Length)
+		litFreq[sym]++
+		sym, _, _ = distanceSymbol(t.Distance)
+		distFreq[sym]++
+	}
+	if len(distFreq) == 0 {
+		// decoders expect at least one distance code
+		distFreq[0] = 1
+	}
+
+	litLengths, err := limitCodeLengths(litFreq, deflateMaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+	distLengths, err := limitCodeLengths(distFreq, deflateMaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.planHeader(litLengths, distLengths); err != nil {
+		return nil, err
+	}
+
+	dynamicBits := 3 + p.headerBits() + tokenBits(litFreq, distFreq, litLengths, distLengths)
+	fixedBits := 3 + tokenBits(litFreq, distFreq, fixedLitLengths, fixedDistLengths)
+	storedBits := (len(data)+maxStoredLen-1)/maxStoredLen*(3+32) + 7 + len(data)*8
+
+	switch {
+	case storedBits < min(dynamicBits, fixedBits):
+		p.typ = deflateStored
+	case dynamicBits < fixedBits:
+		p.typ = deflateDynamic
+		p.codes = newDeflateCodes(litLengths, distLengths)
+	}
+
+	return p, nil
+}
+
+// tokenBits returns the size of the coded tokens, extra bits included.
+func tokenBits(litFreq, distFreq FrequencyMap, litLengths, distLengths map[rune]int) int {
+	total := 0
+	for sym, freq := range litFreq {
+		total += freq * litLengths[sym]
+		if _, n, ok := lengthBase(sym); ok {
+			total += freq * int(n)
+		}
+	}
+	for sym, freq := range distFreq {
+		_, n, _ := distanceBase(sym)
+		total += freq * (distLengths[sym] + int(n))
+	}
+
+	return total
+}
+
+// planHeader run length codes the code lengths of a dynamic block and
+// builds the code length codes.
+func (p *deflatePlan) planHeader(litLengths, distLengths map[rune]int) error {
+	p.numLit = int(endOfBlock) + 1
+	for sym := range litLengths {
+		p.numLit = max(p.numLit, int(sym)+1)
+	}
+	p.numDist = 1
+	for sym := range distLengths {
+		p.numDist = max(p.numDist, int(sym)+1)
+	}
+
+	lengths := make([]int, 0, p.numLit+p.numDist)
+	for i := 0; i < p.numLit; i++ {
+		lengths = append(lengths, litLengths[rune(i)])
+	}
+	for i := 0; i < p.numDist; i++ {
+		lengths = append(lengths, distLengths[rune(i)])
+	}
+	p.codeLenSymbols = runLengthCodeLengths(lengths)
+
+	freq := make(FrequencyMap)
+	for _, s := range p.codeLenSymbols {
+		freq[s.sym]++
+	}
+	codeLenLengths, err := limitCodeLengths(freq, codeLenMaxCodeLen)
+	if err != nil {
+		return err
+	}
+	p.codeLenCodes = newDeflateCodes(codeLenLengths, nil)
+
+	p.numCodeLen = 4
+	for i, sym := range codeLengthOrder {
+		p.codeLenOrder[i] = codeLenLengths[rune(sym)]
+		if p.codeLenOrder[i] != 0 {
+			p.numCodeLen = max(p.numCodeLen, i+1)
+		}
+	}
+
+	return nil
+}
+
+func (p *deflatePlan) headerBits() int {
+	total := 5 + 5 + 4 + 3*p.numCodeLen
+	for _, s := range p.codeLenSymbols {
+		total += int(p.codeLenCodes.lit[s.sym].length) + int(s.n)
+	}
+
+	return total
+}
+
+// runLengthCodeLengths replaces runs in lengths with the repeat symbols of
+// the code length alphabet.
+func runLengthCodeLengths(lengths []int) []codeLenSymbol {
+	var symbols []codeLenSymbol
+	for i := 0; i < len(lengths); {
+		length := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == length {
+			run++
+		}
+		i += run
+
+		if length == 0 {
+			for run >= 11 {
+				n := min(run, 138)
+				symbols = append(symbols, codeLenSymbol{sym: 18, n: 7, extra: uint64(n - 11)})
+				run -= n
+			}
+			if run >= 3 {
+				symbols = append(symbols, codeLenSymbol{sym: 17, n: 3, extra: uint64(run - 3)})
+				run = 0
+			}
+		} else {
+			symbols = append(symbols, codeLenSymbol{sym: rune(length)})
+			run--
+			for run >= 3 {
+				n := min(run, 6)
+				symbols = append(symbols, codeLenSymbol{sym: 16, n: 2, extra: uint64(n - 3)})
+				run -= n
+			}
+		}
+
+		for ; run > 0; run-- {
+			symbols = append(symbols, codeLenSymbol{sym: rune(length)})
+		}
+	}
+
+	return symbols
+}
+
+// write writes the block to bw, final sets BFINAL on its last block.
+func (p *deflatePlan) write(bw *bitio.LSBWriter, final bool) {
+	finalBit := uint64(0)
+	if final {
+		finalBit = 1
+	}
+
+	if p.typ == deflateStored {
+		for data := p.data; len(data) > 0; {
+			n := min(len(data), maxStoredLen)
+			last := uint64(0)
+			if n == len(data) {
+				last = finalBit
+			}
+			bw.WriteBits(last, 1)
+			bw.WriteBits(deflateStored, 2)
+			bw.Align()
+			bw.WriteBits(uint64(n), 16)
+			bw.WriteBits(uint64(^uint16(n)), 16)
+			for _, b := range data[:n] {
+				bw.WriteBits(uint64(b), 8)
+			}
+			data = data[n:]
+		}
+		return
+	}
+
+	bw.WriteBits(finalBit, 1)
+	bw.WriteBits(uint64(p.typ), 2)
+	if p.typ == deflateDynamic {
+		bw.WriteBits(uint64(p.numLit-257), 5)
+		bw.WriteBits(uint64(p.numDist-1), 5)
+		bw.WriteBits(uint64(p.numCodeLen-4), 4)
+		for _, length := range p.codeLenOrder[:p.numCodeLen] {
+			bw.WriteBits(uint64(length), 3)
+		}
+		for _, s := range p.codeLenSymbols {
+			c := p.codeLenCodes.lit[s.sym]
+			bw.WriteBits(c.bits, c.length)
+			bw.WriteBits(s.extra, s.n)
+		}
+	}
+
+	for _, t := range p.tokens {
+		if t.Length == 0 {
+			c := p.codes.lit[t.Literal]
+			bw.WriteBits(c.bits, c.length)
+			continue
+		}
+
+		sym, n, extra := lengthSymbol(t.Length)
+		c := p.codes.lit[sym]
+		bw.WriteBits(c.bits, c.length)
+		bw.WriteBits(extra, n)
+
+		sym, n, extra = distanceSymbol(t.Distance)
+		c = p.codes.dist[sym]
+		bw.WriteBits(c.bits, c.length)
+		bw.WriteBits(extra, n)
+	}
+
+	c := p.codes.lit[endOfBlock]
+	bw.WriteBits(c.bits, c.length)
+}
+
+// GzipWriter compresses everything written to it into gzip format, which
+// any gzip tool can read. Blocks are DEFLATE blocks built with the same
+// LZ77 stage and Huffman code builder as the native format, only the
+// block size and concurrency options apply.
+type GzipWriter struct {
+	w           io.Writer
+	opts        options
+	bw          *bitio.LSBWriter
+	crc         uint32
+	size        uint32
+	buf         []byte
+	wroteHeader bool
+	closed      bool
+	err         error
+}
+
+// NewGzipWriter returns a GzipWriter compressing to w with the given
+// options, an invalid option is reported by the first Write or Close.
+func NewGzipWriter(w io.Writer, opts ...Option) *GzipWriter {
+	z := &GzipWriter{opts: newOptions(opts)}
+	z.Reset(w)
+
+	return z
+}
+
+// Reset discards the GzipWriter state and makes it write to w, keeping
+// the options it was created with.
+func (z *GzipWriter) Reset(w io.Writer) {
+	z.w = w
+	z.bw = bitio.NewLSBWriter(w)
+	z.crc = 0
+	z.size = 0
+	z.buf = z.buf[:0]
+	z.wroteHeader = false
+	z.closed = false
+	z.err = z.opts.validate()
+}
+
+func (z *GzipWriter) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.closed {
+		return 0, errors.New("write to closed GzipWriter")
+	}
+
+	// a full buffer is only flushed once more input arrives, so the last
+	// block written by Close is never empty unless the input is
+	batchSize := z.opts.blockSize * z.opts.concurrency
+	n := 0
+	for len(p) > 0 {
+		if len(z.buf) == batchSize {
+			if z.err = z.flushBlocks(false); z.err != nil {
+				return n, z.err
+			}
+		}
+
+		m := min(len(p), batchSize-len(z.buf))
+		z.buf = append(z.buf, p[:m]...)
+		p = p[m:]
+		n += m
+	}
+
+	return n, nil
+}
+
+// flushBlocks compresses the buffered input into DEFLATE blocks. Unless
+// final is set, input that does not fill a whole block is kept for the
+// next batch. The final call always writes a block, possibly empty, to
+// close the DEFLATE stream.
+func (z *GzipWriter) flushBlocks(final bool) error {
+	var blocks [][]byte
+	data := z.buf
+	for len(data) >= z.opts.blockSize || final && len(data) > 0 {
+		n := min(len(data), z.opts.blockSize)
+		blocks = append(blocks, data[:n])
+		data = data[n:]
+	}
+	if final && len(blocks) == 0 {
+		blocks = append(blocks, nil)
+	}
+
+	plans := make([]*deflatePlan, len(blocks))
+	err := parallel(len(blocks), z.opts.concurrency, func(i int) error {
+		var err error
+		plans[i], err = planDeflate(blocks[i])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !z.wroteHeader {
+		z.wroteHeader = true
+		if _, err := z.w.Write(gzipHeader); err != nil {
+			return err
+		}
+	}
+
+	size := 0
+	for i, p := range plans {
+		p.write(z.bw, final && i == len(plans)-1)
+		z.crc = crc32.Update(z.crc, crc32.IEEETable, p.data)
+		z.size += uint32(len(p.data))
+		size += len(p.data)
+	}
+	z.buf = append(z.buf[:0], z.buf[size:]...)
+
+	return nil
+}
+
+// Close writes the last block and the gzip trailer, it does not close the
+// underlying writer.
+func (z *GzipWriter) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+
+	if z.err = z.flushBlocks(true); z.err != nil {
+		return z.err
+	}
+	if z.err = z.bw.Flush(); z.err != nil {
+		return z.err
+	}
+
+	trailer := binary.LittleEndian.AppendUint32(nil, z.crc)
+	trailer = binary.LittleEndian.AppendUint32(trailer, z.size)
+	_, z.err = z.w.Write(trailer)
+
+	return z.err
+}
+
+// CompressGzip compresses input into gzip format.
+func CompressGzip(input []byte, opts ...Option) ([]byte, error) {
+	var buff bytes.Buffer
+	zw := NewGzipWriter(&buff, opts...)
+	if _, err := zw.Write(input); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
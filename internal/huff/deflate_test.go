@@ -0,0 +1,139 @@
+package huff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"compression_tool.nobletk/internal/bitio"
+)
+
+func gunzip(t *testing.T, compressed []byte) []byte {
+	t.Helper()
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := zr.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return decompressed
+}
+
+func TestGzipReadByStandardLibrary(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	random := make([]byte, 200000)
+	rand.New(rand.NewSource(3)).Read(random)
+
+	tests := []struct {
+		name  string
+		input []byte
+		opts  []Option
+	}{
+		{name: "Empty", input: nil},
+		{name: "Short", input: []byte("hello, gzip")},
+		{name: "Single byte run", input: bytes.Repeat([]byte{0}, 100000)},
+		{name: "Random", input: random},
+		{name: "Small blocks", input: data[:300000], opts: []Option{WithBlockSize(10000), WithConcurrency(4)}},
+		{name: "Test file", input: data},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := CompressGzip(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			assertEqualBytes(t, gunzip(t, compressed), tt.input)
+		})
+	}
+}
+
+func TestGzipSameOutputConcurrently(t *testing.T) {
+	input := []byte(strings.Repeat("concurrent deflate blocks, ", 20000))
+
+	serial, err := CompressGzip(input, WithBlockSize(20000))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	concurrent, err := CompressGzip(input, WithBlockSize(20000), WithConcurrency(8))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqualBytes(t, concurrent, serial)
+}
+
+func TestPlanDeflateBlockType(t *testing.T) {
+	random := make([]byte, 5000)
+	rand.New(rand.NewSource(4)).Read(random)
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected int
+	}{
+		{"Empty", nil, deflateFixed},
+		{"Short text", []byte("abc"), deflateFixed},
+		{"Random", random, deflateStored},
+		{"Long text", []byte(strings.Repeat("skewed text, mostly eeee ", 500)), deflateDynamic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := planDeflate(tt.input)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			assertEqual(t, p.typ, tt.expected)
+
+			// every block type is also a valid DEFLATE stream on its own
+			var buff bytes.Buffer
+			bw := bitio.NewLSBWriter(&buff)
+			p.write(bw, true)
+			if err := bw.Flush(); err != nil {
+				t.Fatal(err.Error())
+			}
+		})
+	}
+}
+
+func TestRunLengthCodeLengths(t *testing.T) {
+	lengths := []int{3, 3, 3, 3, 3, 3, 3, 3, 0, 0, 0, 0, 2}
+	lengths = append(lengths, make([]int, 150)...)
+	lengths = append(lengths, 5, 5, 0, 0)
+
+	expected := []codeLenSymbol{
+		{sym: 3},
+		{sym: 16, n: 2, extra: 3},
+		{sym: 3},
+		{sym: 17, n: 3, extra: 1},
+		{sym: 2},
+		{sym: 18, n: 7, extra: 127},
+		{sym: 18, n: 7, extra: 1},
+		{sym: 5},
+		{sym: 5},
+		{sym: 0},
+		{sym: 0},
+	}
+
+	actual := runLengthCodeLengths(lengths)
+	assertEqual(t, len(actual), len(expected))
+	for i := range expected {
+		assertEqual(t, actual[i], expected[i])
+	}
+}
@@ -0,0 +1,127 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"unicode/utf8"
+)
+
+// dictMagic starts every serialized Dictionary.
+var dictMagic = []byte{'H', 'U', 'F', 'D'}
+
+var ErrDictionaryMismatch = errors.New("dictionary does not match the stream")
+
+// Dictionary is a Huffman table trained on sample data. A stream written
+// with it only stores its ID, and its first blocks are coded with the
+// table instead of storing one of their own, which pays off for small
+// inputs where the table would take a large share of the output.
+type Dictionary struct {
+	id      uint32
+	lengths map[rune]int
+}
+
+// TrainDictionary builds a dictionary from the rune frequencies of
+// samples, with codes of at most maxCodeLen bits unless it is zero. Every
+// ASCII symbol gets a code even when the samples lack it, so inputs with
+// an unexpected character can still use the table. An id of zero is
+// replaced with the CRC32 of the table.
+func TrainDictionary(id uint32, samples [][]byte, maxCodeLen int) (*Dictionary, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("no training samples")
+	}
+	if maxCodeLen < 0 || maxCodeLen > maxCodeLength {
+		return nil, fmt.Errorf("invalid max code length: %d", maxCodeLen)
+	}
+
+	freqMap := make(FrequencyMap)
+	for char := rune(0); char < utf8.RuneSelf; char++ {
+		freqMap[char] = 1
+	}
+	for i, sample := range samples {
+		sampleFreq, err := getRunesFrequency(sample)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+		for char, freq := range sampleFreq {
+			freqMap[char] += freq
+		}
+	}
+
+	lengths, err := buildLengths(freqMap, maxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		id = crc32.ChecksumIEEE(serializeLengths(lengths))
+	}
+
+	return &Dictionary{id: id, lengths: lengths}, nil
+}
+
+// ID returns the number a stream stores to name the dictionary.
+func (d *Dictionary) ID() uint32 {
+	return d.id
+}
+
+// Bytes serializes the dictionary for ParseDictionary: dictMagic, the ID
+// as a 4 byte big-endian integer and the code length table.
+func (d *Dictionary) Bytes() []byte {
+	b := append([]byte(nil), dictMagic...)
+	b = binary.BigEndian.AppendUint32(b, d.id)
+
+	return append(b, serializeLengths(d.lengths)...)
+}
+
+// ParseDictionary reads a dictionary written by Bytes.
+func ParseDictionary(data []byte) (*Dictionary, error) {
+	if len(data) < len(dictMagic)+dictIDSize || !bytes.Equal(data[:len(dictMagic)], dictMagic) {
+		return nil, errors.New("invalid dictionary")
+	}
+
+	lengths, err := deserializeLengths(data[len(dictMagic)+dictIDSize:])
+	if err != nil {
+		return nil, err
+	}
+	if len(lengths) == 0 {
+		return nil, errors.New("dictionary without code lengths")
+	}
+
+	return &Dictionary{id: binary.BigEndian.Uint32(data[len(dictMagic):]), lengths: lengths}, nil
+}
+
+// encodeModel returns the model blocks reusing the dictionary are coded
+// with.
+func (d *Dictionary) encodeModel() *huffmanModel {
+	return &huffmanModel{lengths: d.lengths, codes: canonicalCodes(d.lengths)}
+}
+
+// decodeModel returns the model the first blocks of a stream with header h
+// reuse, checking that the stream was written with d.
+func (d *Dictionary) decodeModel(h header) (Model, error) {
+	if d == nil {
+		return nil, fmt.Errorf("%w: stream needs dictionary %d", ErrDictionaryMismatch, h.dictID)
+	}
+	if d.id != h.dictID {
+		return nil, fmt.Errorf("%w: stream needs dictionary %d, got %d", ErrDictionaryMismatch, h.dictID, d.id)
+	}
+
+	return coders[MethodHuffman].ReadModel(serializeLengths(d.lengths), h.coderConfig())
+}
+
+// newBlockDecoder returns the decoder of the blocks of a stream with header
+// h, starting out with the table of dict when the stream uses one.
+func newBlockDecoder(h header, dict *Dictionary) (blockDecoder, error) {
+	if !h.dictionary() {
+		return blockDecoder{h: h}, nil
+	}
+
+	m, err := dict.decodeModel(h)
+	if err != nil {
+		return blockDecoder{}, err
+	}
+
+	return blockDecoder{h: h, model: m}, nil
+}
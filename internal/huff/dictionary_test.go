@@ -0,0 +1,189 @@
+package huff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func jsonSamples(n int) [][]byte {
+	samples := make([][]byte, n)
+	for i := range samples {
+		samples[i] = []byte(fmt.Sprintf(`{"id":%d,"user":"user%d","event":"login","status":"ok"}`, i, i))
+	}
+
+	return samples
+}
+
+func TestDictionaryRoundTrip(t *testing.T) {
+	dict, err := TrainDictionary(7, jsonSamples(100), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		opts  []Option
+	}{
+		{name: "Message", input: []byte(`{"id":1000,"user":"alice","event":"logout","status":"ok"}`)},
+		{name: "Rune missing from the samples", input: []byte(`{"user":"zoë"}`)},
+		{name: "Many blocks", input: bytes.Join(jsonSamples(200), []byte("\n")), opts: []Option{WithBlockSize(256), WithConcurrency(4)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRoundTrip(t, tt.input, append([]Option{WithDictionary(dict)}, tt.opts...), WithDictionary(dict), WithConcurrency(2))
+		})
+	}
+}
+
+func TestDictionaryShrinksSmallInput(t *testing.T) {
+	dict, err := TrainDictionary(0, jsonSamples(100), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	input := []byte(`{"id":1000,"user":"alice","event":"logout","status":"ok"}`)
+
+	plain, err := Compress(input)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	withDict, err := Compress(input, WithDictionary(dict))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(withDict) >= len(plain) {
+		t.Errorf("size with dictionary got %d, want less than %d", len(withDict), len(plain))
+	}
+}
+
+func TestDictionaryMismatch(t *testing.T) {
+	dict, err := TrainDictionary(1, jsonSamples(10), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	other, err := TrainDictionary(2, jsonSamples(10), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	compressed, err := Compress([]byte(`{"id":1}`), WithDictionary(dict))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		expectedErr string
+	}{
+		{"Without dictionary", nil, "dictionary does not match the stream: stream needs dictionary 1"},
+		{"Other dictionary", []Option{WithDictionary(other)}, "dictionary does not match the stream: stream needs dictionary 1, got 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decompress(compressed, tt.opts...)
+			if !errors.Is(err, ErrDictionaryMismatch) || err.Error() != tt.expectedErr {
+				t.Errorf("Decompress() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestDictionaryBytes(t *testing.T) {
+	dict, err := TrainDictionary(0, jsonSamples(10), 9)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if dict.ID() == 0 {
+		t.Error("derived dictionary ID got 0")
+	}
+
+	parsed, err := ParseDictionary(dict.Bytes())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, parsed.ID(), dict.ID())
+	assertEqual(t, printSortedMap(parsed.lengths), printSortedMap(dict.lengths))
+
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Short", []byte("HUFD"), "invalid dictionary"},
+		{"Wrong magic", []byte("HUFI\x00\x00\x00\x01\x61\x01\x01"), "invalid dictionary"},
+		{"No table", []byte("HUFD\x00\x00\x00\x01"), "dictionary without code lengths"},
+		{"Bad table", []byte("HUFD\x00\x00\x00\x01\x61\x02\x01"), "code lengths are truncated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDictionary(tt.input)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("ParseDictionary() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestDictionaryOptionErrors(t *testing.T) {
+	dict, err := TrainDictionary(1, jsonSamples(10), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		expectedErr string
+	}{
+		{"Other method", []Option{WithMethod(MethodLZ77)}, "dictionary is not supported by method 2"},
+		{"Codes too long", []Option{WithMaxCodeLength(7)}, "dictionary: code length"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithDictionary(dict)}, tt.opts...)
+			_, err := Compress([]byte("{}"), opts...)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expectedErr) {
+				t.Errorf("Compress() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+
+	_, err = TrainDictionary(1, nil, 0)
+	if err == nil || err.Error() != "no training samples" {
+		t.Errorf("TrainDictionary() error got=%v, want=no training samples", err)
+	}
+}
+
+func TestSeekableWithDictionary(t *testing.T) {
+	dict, err := TrainDictionary(3, jsonSamples(100), 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	input := bytes.Join(jsonSamples(200), []byte("\n"))
+	compressed, err := Compress(input, WithDictionary(dict), WithIndex(), WithBlockSize(512))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed))); !errors.Is(err, ErrDictionaryMismatch) {
+		t.Errorf("OpenSeekable() error got=%v, want %v", err, ErrDictionaryMismatch)
+	}
+
+	s, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed)), WithDictionary(dict))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	part := make([]byte, 1000)
+	if _, err := s.ReadAt(part, 3000); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, part, input[3000:4000])
+}
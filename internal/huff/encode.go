@@ -4,52 +4,52 @@ import (
 	"bytes"
 	"errors"
 	"unicode/utf8"
+
+	"compression_tool.nobletk/internal/bitio"
 )
 
 type FrequencyMap map[rune]int
 
-func Compress(input []byte) ([]byte, error) {
-	runesFreq, err := getRunesFrequency(input)
-	if err != nil {
+func Compress(input []byte, opts ...Option) ([]byte, error) {
+	var outBuff bytes.Buffer
+
+	zw := NewWriter(&outBuff, opts...)
+	if _, err := zw.Write(input); err != nil {
 		return nil, err
 	}
-
-	treeRoot, prefixTable, err := buildTree(runesFreq)
-	if err != nil {
+	if err := zw.Close(); err != nil {
 		return nil, err
 	}
 
-	treeBuff, err := serializeTree(treeRoot)
-	if err != nil {
-		return nil, err
+	return outBuff.Bytes(), nil
+}
+
+// buildLengths returns the code lengths for freqMap, limited to maxLen bits
+// when it is not zero.
+func buildLengths(freqMap FrequencyMap, maxLen int) (map[rune]int, error) {
+	if maxLen > 0 {
+		return limitCodeLengths(freqMap, maxLen)
 	}
 
-	bitBuff, totalBits, err := encData(input, prefixTable)
+	treeRoot, _, err := buildTree(freqMap)
 	if err != nil {
 		return nil, err
 	}
 
-	var outBuff bytes.Buffer
-	validBits := byte(totalBits % 8)
-	if validBits == 0 {
-		validBits = 8
-	}
-	outBuff.WriteByte(validBits)
-	outBuff.WriteByte(byte(0x1F)) //unit separator
-	outBuff.Write(treeBuff.Bytes())
-	outBuff.WriteString("%%")
-	outBuff.Write(bitBuff.Bytes())
-
-	return outBuff.Bytes(), nil
+	return codeLengths(treeRoot), nil
 }
 
 func getRunesFrequency(input []byte) (FrequencyMap, error) {
+	return getSymbolsFrequency(input, AlphabetRunes)
+}
+
+func getSymbolsFrequency(input []byte, alphabet Alphabet) (FrequencyMap, error) {
 	freqMap := make(FrequencyMap, 0)
 
 	for i := 0; i < len(input); {
-		char, sz := utf8.DecodeRune(input[i:])
-		if char == utf8.RuneError {
-			return nil, errors.New("invalid UTF-8 encoding")
+		char, sz, err := alphabet.next(input[i:])
+		if err != nil {
+			return nil, err
 		}
 		freqMap[char]++
 		i += sz
@@ -88,39 +88,40 @@ func serializeNode(node *huffmanNode, buff *bytes.Buffer) error {
 	return nil
 }
 
-func encData(data []byte, preTab map[rune]string) (bytes.Buffer, int, error) {
+func encData(data []byte, preTab prefixTable, alphabet Alphabet) (bytes.Buffer, int, error) {
 	var bitBuff bytes.Buffer
-	var currentByte byte
-	bitCount := 0
-	totalBits := 0
+	bw := bitio.NewBitWriter(&bitBuff)
+
+	// single byte symbols are looked up in an array instead of the map
+	var small [256]code
+	for char, c := range preTab {
+		if char < 256 {
+			small[char] = c
+		}
+	}
 
 	for i := 0; i < len(data); {
-		char, sz := utf8.DecodeRune(data[i:])
-		if char == utf8.RuneError {
-			return bytes.Buffer{}, 0, errors.New("invalid UTF-8 encoding")
+		var c code
+		if data[i] < utf8.RuneSelf || alphabet == AlphabetBytes {
+			c = small[data[i]]
+			i++
+		} else {
+			char, sz, err := alphabet.next(data[i:])
+			if err != nil {
+				return bytes.Buffer{}, 0, err
+			}
+			i += sz
+			c = preTab[char]
 		}
-		i += sz
-		code, exists := preTab[char]
-		if !exists {
+		if c.length == 0 {
 			return bytes.Buffer{}, 0, errors.New("char not found in prefix table")
 		}
-		for _, bit := range code {
-			if bit == '1' {
-				currentByte |= (1 << (7 - bitCount))
-			}
-			bitCount++
-			totalBits++
-			if bitCount == 8 {
-				bitBuff.WriteByte(currentByte)
-				currentByte = 0
-				bitCount = 0
-			}
-		}
+		bw.WriteBits(c.bits, c.length)
 	}
 
-	if bitCount > 0 {
-		bitBuff.WriteByte(currentByte)
+	if err := bw.Flush(); err != nil {
+		return bytes.Buffer{}, 0, err
 	}
 
-	return bitBuff, totalBits, nil
+	return bitBuff, int(bw.BitsWritten()), nil
 }
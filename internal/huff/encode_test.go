@@ -18,6 +18,18 @@ func TestGetRunesFrequency(t *testing.T) {
 	assertEqual(t, actualFreqMap, expectedFreqMap)
 }
 
+func TestGetSymbolsFrequencyBytes(t *testing.T) {
+	input := []byte{0xff, 0xfe, 0xff, 'a', 0xc3, 0xa9}
+
+	frequencyMap, err := getSymbolsFrequency(input, AlphabetBytes)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := map[rune]int{0xff: 2, 0xfe: 1, 'a': 1, 0xc3: 1, 0xa9: 1}
+	assertEqual(t, printSortedMap(frequencyMap), printSortedMap(expected))
+}
+
 func TestEncodeData(t *testing.T) {
 	tests := []struct {
 		input             []byte
@@ -42,7 +54,7 @@ func TestEncodeData(t *testing.T) {
 	for _, tt := range tests {
 		t.Helper()
 
-		buff, actualTotalBits, err := encData(tt.input, tt.prefixTable)
+		buff, actualTotalBits, err := encData(tt.input, stringCodes(tt.prefixTable), AlphabetRunes)
 		if err != nil {
 			t.Fatalf(err.Error())
 		}
@@ -54,17 +66,106 @@ func TestEncodeData(t *testing.T) {
 }
 
 func TestCompress(t *testing.T) {
-	input := []byte{'a', 'a', 'a', 'a', ' ', 'b', 'b', 'b', ' ', 'c', 'c', ' ', 'd'}
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{
+			name:  "Huffman block",
+			input: []byte("aaaa bbb cc daaaa bbb cc d"),
+			expected: []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 0, 0,
+				1, 9, 32, 1, 2, 64, 4, 2, 2, 3, 3,
+				58, 85, 42, 54, 58, 169, 81, 177, 192,
+				0,
+				22, 222, 22, 161, 0, 0, 0, 0, 0, 0, 0, 26},
+		},
+		{
+			// the table costs more than coding saves
+			name:  "Stored block",
+			input: []byte{'a', 'a', 'a', 'a', ' ', 'b', 'b', 'b', ' ', 'c', 'c', ' ', 'd'},
+			expected: []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 0, 0,
+				4, 13, 'a', 'a', 'a', 'a', ' ', 'b', 'b', 'b', ' ', 'c', 'c', ' ', 'd',
+				0,
+				244, 43, 22, 3, 0, 0, 0, 0, 0, 0, 0, 13},
+		},
+	}
 
-	actualEncodedText, err := Compress(input)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualEncodedText, err := Compress(tt.input)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			assertEqualBytes(t, actualEncodedText, tt.expected)
+		})
+	}
+}
+
+func TestCompressRoundTripEdgeCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		alphabet Alphabet
+	}{
+		{"Empty", []byte{}, AlphabetRunes},
+		{"Empty bytes", []byte{}, AlphabetBytes},
+		{"Single symbol", []byte("aaaa"), AlphabetRunes},
+		{"Single rune", []byte("é"), AlphabetRunes},
+		{"Single byte", []byte{0xff, 0xff, 0xff}, AlphabetBytes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := Compress(tt.input, WithAlphabet(tt.alphabet))
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			decompressed, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			assertEqualBytes(t, decompressed, tt.input)
+		})
+	}
+}
+
+func TestCompressDeterministic(t *testing.T) {
+	input := []byte("the quick brown fox jumps over the lazy dog, ÀÉÎÕÜ 0123456789")
+
+	expected, err := Compress(input)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
 
-	expectedEncodedText := []byte{5, 31, 0, 0, 0, 1, 100, 1, 99, 1, 32, 0, 1, 98, 1,
-		97, 37, 37, 255, 106, 73, 64}
+	for i := 0; i < 20; i++ {
+		actual, err := Compress(input)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		assertEqualBytes(t, actual, expected)
+	}
+}
+
+func TestCompressChecksums(t *testing.T) {
+	input := []byte("aaaa bbb cc d")
+
+	for _, c := range []Checksum{ChecksumCRC32, ChecksumCRC64, ChecksumSHA256} {
+		compressed, err := Compress(input, WithChecksum(c))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		decompressed, err := Decompress(compressed)
+		if err != nil {
+			t.Fatalf("checksum %d: %v", c, err)
+		}
 
-	assertEqualBytes(t, actualEncodedText, expectedEncodedText)
+		assertEqual(t, string(decompressed), string(input))
+	}
 }
 
 func TestCompressInvalidUTF8(t *testing.T) {
@@ -81,6 +182,28 @@ func TestCompressInvalidUTF8(t *testing.T) {
 	}
 }
 
+func TestCompressByteMode(t *testing.T) {
+	input := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0xff, 0xfe, 0xff, 0xc3, 0xa9}
+
+	compressed, err := Compress(input, WithAlphabet(AlphabetBytes))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	h, err := readHeader(compressed)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assertEqual(t, h.alphabet(), AlphabetBytes)
+
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	assertEqualBytes(t, decompressed, input)
+}
+
 func TestSerializTreeNilNode(t *testing.T) {
 	_, err := serializeTree(nil)
 	if err == nil {
@@ -100,7 +223,7 @@ func TestEndDataMissingCharInPrefixTable(t *testing.T) {
 		'b': "01",
 	}
 
-	_, _, err := encData(validUTF8, preTab)
+	_, _, err := encData(validUTF8, stringCodes(preTab), AlphabetRunes)
 	if err == nil {
 		t.Fatal("expected error for missing char in prefix table, got nil")
 	}
@@ -111,3 +234,17 @@ func TestEndDataMissingCharInPrefixTable(t *testing.T) {
 	}
 
 }
+
+func BenchmarkEncode(b *testing.B) {
+	data, prefix, _, _ := benchmarkPayload(b)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, _, err := encData(data, prefix, AlphabetRunes); err != nil {
+			b.Fatal(err.Error())
+		}
+	}
+}
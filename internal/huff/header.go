@@ -0,0 +1,175 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var magicNumber = []byte{'H', 'U', 'F', 0x1A}
+
+const (
+	// formatVersion stores the method and the feature flags in bytes of
+	// their own. Versions 1 and 2 kept the method in three flag bits and
+	// are no longer read.
+	formatVersion byte = 3
+	headerSize         = 9
+)
+
+const (
+	flagChecksumMask  byte = 0x03
+	flagAlphabetMask  byte = 0x0C
+	flagAlphabetShift      = 2
+	// flagIndex marks a stream followed by a block index.
+	flagIndex byte = 0x10
+)
+
+// knownFlags holds every flag bit this version understands, anything else
+// in the flags byte is rejected.
+const knownFlags = flagChecksumMask | flagAlphabetMask | flagIndex
+
+const (
+	// featureRLE marks Huffman blocks coded with run symbols.
+	featureRLE byte = 0x01
+	// featureDictionary marks a stream whose first table is the one of a
+	// Dictionary, its ID follows the feature flags.
+	featureDictionary byte = 0x02
+)
+
+const knownFeatures = featureRLE | featureDictionary
+
+// dictIDSize is the size of the dictionary ID stored in the header.
+const dictIDSize = 4
+
+var (
+	ErrInvalidMagic       = errors.New("invalid magic number")
+	ErrUnsupportedVersion = errors.New("unsupported format version")
+)
+
+type header struct {
+	version byte
+	flags   byte
+	// maxCodeLen is the longest code length the table may use, zero when
+	// the codes are not length-limited.
+	maxCodeLen byte
+	method     Method
+	features   byte
+	// dictID is only stored with featureDictionary.
+	dictID uint32
+}
+
+// size returns the number of bytes the header takes in the stream.
+func (h header) size() int {
+	if h.dictionary() {
+		return headerSize + dictIDSize
+	}
+
+	return headerSize
+}
+
+func (h header) checksum() Checksum {
+	return Checksum(h.flags & flagChecksumMask)
+}
+
+func (h header) alphabet() Alphabet {
+	return Alphabet((h.flags & flagAlphabetMask) >> flagAlphabetShift)
+}
+
+func (h header) coderConfig() CoderConfig {
+	return CoderConfig{Alphabet: h.alphabet(), MaxCodeLen: int(h.maxCodeLen), RunLength: h.rle()}
+}
+
+func (h header) rle() bool {
+	return h.features&featureRLE != 0
+}
+
+func (h header) dictionary() bool {
+	return h.features&featureDictionary != 0
+}
+
+func writeHeader(buff *bytes.Buffer, h header) {
+	buff.Write(magicNumber)
+	buff.WriteByte(h.version)
+	buff.WriteByte(h.flags)
+	buff.WriteByte(h.maxCodeLen)
+	buff.WriteByte(byte(h.method))
+	buff.WriteByte(h.features)
+	if h.dictionary() {
+		buff.Write(binary.BigEndian.AppendUint32(nil, h.dictID))
+	}
+}
+
+// readStreamHeader reads a header from r, with the dictionary ID when the
+// features call for one.
+func readStreamHeader(r io.Reader) (header, error) {
+	buff := make([]byte, headerSize+dictIDSize)
+	if _, err := io.ReadFull(r, buff[:headerSize]); err != nil {
+		return header{}, truncated(err, errors.New("data is too short"))
+	}
+	if !bytes.Equal(buff[:len(magicNumber)], magicNumber) || buff[4] != formatVersion || buff[headerSize-1]&featureDictionary == 0 {
+		return readHeader(buff[:headerSize])
+	}
+
+	if _, err := io.ReadFull(r, buff[headerSize:]); err != nil {
+		return header{}, truncated(err, errors.New("data is too short"))
+	}
+
+	return readHeader(buff)
+}
+
+func readHeader(data []byte) (header, error) {
+	if len(data) < len(magicNumber)+1 {
+		return header{}, errors.New("data is too short")
+	}
+	if !bytes.Equal(data[:len(magicNumber)], magicNumber) {
+		return header{}, ErrInvalidMagic
+	}
+	if data[4] != formatVersion {
+		return header{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, data[4])
+	}
+	if len(data) < headerSize {
+		return header{}, errors.New("data is too short")
+	}
+
+	h := header{
+		version:    data[4],
+		flags:      data[5],
+		maxCodeLen: data[6],
+		method:     Method(data[7]),
+		features:   data[8],
+	}
+	if h.dictionary() {
+		if len(data) < headerSize+dictIDSize {
+			return header{}, errors.New("data is too short")
+		}
+		h.dictID = binary.BigEndian.Uint32(data[headerSize:])
+	}
+	if h.flags&^knownFlags != 0 {
+		return header{}, fmt.Errorf("unknown header flags: %#02x", h.flags&^knownFlags)
+	}
+	if h.checksum() > ChecksumSHA256 {
+		return header{}, fmt.Errorf("unknown checksum: %d", h.checksum())
+	}
+	if h.alphabet() > AlphabetBytes {
+		return header{}, fmt.Errorf("unknown alphabet: %d", h.alphabet())
+	}
+	if !h.method.valid() {
+		return header{}, fmt.Errorf("unknown method: %d", h.method)
+	}
+	if h.maxCodeLen > maxCodeLength {
+		return header{}, fmt.Errorf("invalid max code length: %d", h.maxCodeLen)
+	}
+	if h.features&^knownFeatures != 0 {
+		return header{}, fmt.Errorf("unknown header features: %#02x", h.features&^knownFeatures)
+	}
+	if h.rle() && h.method != MethodHuffman {
+		return header{}, fmt.Errorf("run-length coding is not supported by method %d", h.method)
+	}
+	if h.dictionary() && h.method != MethodHuffman {
+		return header{}, fmt.Errorf("dictionary is not supported by method %d", h.method)
+	}
+
+	return h, nil
+}
@@ -29,10 +29,10 @@ func printTree(node *huffmanNode, level int) {
 	printTree(node.Left, level+1)
 }
 
-func printPrefixTable(prefix map[rune]string) string {
+func printPrefixTable(prefix prefixTable) string {
 	type pair struct {
 		key   rune
-		value string
+		value code
 	}
 
 	var pairs []pair
@@ -110,7 +110,22 @@ func byteSlicesEqual(a, b []byte) bool {
 	return true
 }
 
-func sortRunes(pt map[rune]string) []rune {
+// stringCodes builds a prefix table from codes written as '0'/'1' strings.
+func stringCodes(codes map[rune]string) prefixTable {
+	preTab := make(prefixTable, len(codes))
+	for char, s := range codes {
+		var c code
+		for _, bit := range s {
+			c.bits = c.bits<<1 | uint64(bit-'0')
+			c.length++
+		}
+		preTab[char] = c
+	}
+
+	return preTab
+}
+
+func sortRunes(pt prefixTable) []rune {
 	runes := make([]rune, 0, len(pt))
 	for r := range pt {
 		runes = append(runes, r)
@@ -143,3 +158,89 @@ func assertEqualBytes(t *testing.T, actual, expected []byte) {
 		}
 	}
 }
+
+// byteLookup and decodeLinear are the original decoder, which scans every
+// code for each bit. They are kept as a reference for benchmarks.
+type byteLookup struct {
+	code []byte
+	char rune
+}
+
+func getEncLookup(pt prefixTable) []byteLookup {
+	lookup := make([]byteLookup, 0, len(pt))
+
+	for char, code := range pt {
+		bl := byteLookup{
+			code: []byte(code.String()),
+			char: char,
+		}
+		lookup = append(lookup, bl)
+	}
+
+	return lookup
+}
+
+func decodeLinear(enc []byte, lookup []byteLookup, totalBits int, alphabet Alphabet) ([]byte, error) {
+	decompressed := make([]byte, 0)
+	currentBits := make([]byte, 0)
+
+	bitIndex := 0
+
+	for i := 0; i < len(enc); i++ {
+		for bit := 7; bit >= 0; bit-- {
+			if bitIndex >= totalBits {
+				break
+			}
+
+			currentBit := ((enc[i] >> bit) & 1) + '0'
+			currentBits = append(currentBits, byte(currentBit))
+
+			for _, entry := range lookup {
+				if byteSlicesEqual(currentBits, entry.code) {
+					decompressed = alphabet.appendSymbol(decompressed, entry.char)
+					currentBits = make([]byte, 0)
+					break
+				}
+			}
+			bitIndex++
+		}
+	}
+
+	return decompressed, nil
+}
+
+// codeBlock codes data with c and returns the serialized model, the
+// payload and its size in bits.
+func codeBlock(t *testing.T, c Coder, data []byte, cfg CoderConfig) ([]byte, []byte, int) {
+	t.Helper()
+
+	m, err := c.BuildModel(data, cfg)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	payload, totalBits, err := c.Encode(m, data, cfg)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return c.WriteModel(m), payload, totalBits
+}
+
+// assertRoundTrip compresses input with opts and checks that decompressing
+// the output with decodeOpts gives input back.
+func assertRoundTrip(t *testing.T, input []byte, opts []Option, decodeOpts ...Option) {
+	t.Helper()
+
+	compressed, err := Compress(input, opts...)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decompressed, err := Decompress(compressed, decodeOpts...)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(decompressed, input) {
+		t.Fatal("decompressed data differs from the original")
+	}
+}
@@ -3,12 +3,13 @@ package huff
 import (
 	"container/heap"
 	"errors"
+	"sort"
 )
 
 type huffmanNode struct {
 	Char  rune
 	Count int
-	Code  string
+	Code  code
 	Left  *huffmanNode
 	Right *huffmanNode
 }
@@ -43,11 +44,28 @@ func (pq *priorityQueue) Pop() any {
 	return item
 }
 
-type prefixTable map[rune]string
+// code is a prefix code stored in the low length bits of bits.
+type code struct {
+	bits   uint64
+	length uint8
+}
+
+func (c code) String() string {
+	buff := make([]byte, c.length)
+	for i := range buff {
+		buff[i] = '0' + byte(c.bits>>(int(c.length)-1-i)&1)
+	}
+
+	return string(buff)
+}
 
+type prefixTable map[rune]code
+
+// buildTree returns a nil root and an empty table for an empty frequency
+// map, a single symbol becomes a leaf root coded with one bit.
 func buildTree(freqMap map[rune]int) (*huffmanNode, prefixTable, error) {
 	if len(freqMap) == 0 {
-		return nil, nil, errors.New("frequency map is empty")
+		return nil, prefixTable{}, nil
 	}
 
 	pq := make(priorityQueue, len(freqMap))
@@ -59,12 +77,10 @@ func buildTree(freqMap map[rune]int) (*huffmanNode, prefixTable, error) {
 		pq[i] = &huffmanNode{Char: char, Count: freq}
 		i++
 	}
+	// start from a sorted queue so the tree does not depend on map order
+	sort.Sort(pq)
 	heap.Init(&pq)
 
-	if len(pq) < 2 {
-		return nil, nil, errors.New("priority queue must contain at least two nodes")
-	}
-
 	count := 1
 	for len(pq) > 1 {
 		left := heap.Pop(&pq).(*huffmanNode)
@@ -86,23 +102,27 @@ func buildTree(freqMap map[rune]int) (*huffmanNode, prefixTable, error) {
 	}
 
 	prefix := make(prefixTable, len(freqMap))
-	encodeTree(head, "", prefix)
+	encodeTree(head, code{}, prefix)
 
 	return head, prefix, nil
 }
 
-func encodeTree(node *huffmanNode, code string, preTab prefixTable) {
+func encodeTree(node *huffmanNode, c code, preTab prefixTable) {
 	if node == nil {
 		return
 	}
 
 	if node.Left == nil && node.Right == nil {
-		node.Code = code
-		preTab[node.Char] = code
+		if c.length == 0 {
+			// a leaf root is the only symbol, give it a one bit code
+			c.length = 1
+		}
+		node.Code = c
+		preTab[node.Char] = c
 	}
 
-	encodeTree(node.Left, code+"0", preTab)
-	encodeTree(node.Right, code+"1", preTab)
+	encodeTree(node.Left, code{bits: c.bits << 1, length: c.length + 1}, preTab)
+	encodeTree(node.Right, code{bits: c.bits<<1 | 1, length: c.length + 1}, preTab)
 }
 
 func getTreeFrequency(root *huffmanNode) []*huffmanNode {
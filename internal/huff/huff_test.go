@@ -79,9 +79,8 @@ func TestInvalidBuildTree(t *testing.T) {
 		freqMap     map[rune]int
 		expectedErr string
 	}{
-		{"Empty frequency map", map[rune]int{}, "frequency map is empty"},
 		{"Zero frequency", map[rune]int{'a': 0}, "frequency must be greater than zero"},
-		{"Single node", map[rune]int{'a': 1}, "priority queue must contain at least two nodes"},
+		{"Negative frequency", map[rune]int{'a': 2, 'b': -1}, "frequency must be greater than zero"},
 	}
 
 	for _, tt := range tests {
@@ -94,6 +93,26 @@ func TestInvalidBuildTree(t *testing.T) {
 	}
 }
 
+func TestBuildTreeEdgeCases(t *testing.T) {
+	root, prefixTable, err := buildTree(map[rune]int{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if root != nil {
+		t.Errorf("root for empty frequency map got=%v, want=nil", root)
+	}
+	assertEqual(t, len(prefixTable), 0)
+
+	root, prefixTable, err = buildTree(map[rune]int{'a': 4})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if root == nil || root.Left != nil || root.Right != nil {
+		t.Fatal("root for single symbol is not a leaf")
+	}
+	assertEqual(t, printPrefixTable(prefixTable), "prefixTable(1):\n(a -- 97: 0)\n")
+}
+
 func TestEncodeTree(t *testing.T) {
 	root := &huffmanNode{
 		Char:  0,
@@ -144,7 +163,7 @@ func TestEncodeTree(t *testing.T) {
 	}
 
 	prefixTable := make(prefixTable, 0)
-	encodeTree(root, "", prefixTable)
+	encodeTree(root, code{}, prefixTable)
 
 	if len(prefixTable) != len(expectedPrefix) {
 		t.Fatalf("len(prefixTable)=%d, len(expectedPrefix)=%d",
@@ -152,7 +171,7 @@ func TestEncodeTree(t *testing.T) {
 	}
 
 	actualPrefix := printPrefixTable(prefixTable)
-	expectedSortedPrefix := printPrefixTable(expectedPrefix)
+	expectedSortedPrefix := printPrefixTable(stringCodes(expectedPrefix))
 
 	assertEqual(t, actualPrefix, expectedSortedPrefix)
 }
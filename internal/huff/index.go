@@ -0,0 +1,463 @@
+package huff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// indexMagic closes the footer of a stream written with an index, so the
+// index can be found from the end of the file.
+var indexMagic = []byte{'H', 'U', 'F', 'I'}
+
+// indexFooterSize is the size of the index length followed by indexMagic.
+const indexFooterSize = 8 + 4
+
+var ErrNoIndex = errors.New("stream has no index")
+
+// indexEntry locates one data block of a stream.
+type indexEntry struct {
+	// offset is the position of the block in the compressed stream.
+	offset int64
+	// pos is the position of the first byte of the block in the
+	// decompressed data.
+	pos  int64
+	size int64
+	// table is the entry of the block storing the table this block is
+	// coded with, the entry itself for a block with its own table and -1
+	// for a block coded with the table of the dictionary.
+	table int
+}
+
+// writeIndex writes the entries and the footer. Every entry stores the
+// distance from the previous block offset, the decompressed size and the
+// distance back to the entry holding its table, all as uvarints.
+func writeIndex(buff *bytes.Buffer, entries []indexEntry) {
+	var index []byte
+	index = binary.AppendUvarint(index, uint64(len(entries)))
+
+	prev := int64(0)
+	for i, e := range entries {
+		index = binary.AppendUvarint(index, uint64(e.offset-prev))
+		index = binary.AppendUvarint(index, uint64(e.size))
+		index = binary.AppendUvarint(index, uint64(i-e.table))
+		prev = e.offset
+	}
+
+	buff.Write(index)
+	buff.Write(binary.BigEndian.AppendUint64(nil, uint64(len(index))))
+	buff.Write(indexMagic)
+}
+
+// countingByteReader counts the bytes read through it.
+type countingByteReader struct {
+	r io.ByteReader
+	n int64
+}
+
+func (c *countingByteReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+
+	return b, err
+}
+
+// readIndex reads the entries written by writeIndex, without the footer,
+// and returns the number of bytes they take.
+func readIndex(r io.ByteReader) ([]indexEntry, int64, error) {
+	cr := &countingByteReader{r: r}
+
+	count, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return nil, 0, truncated(err, errors.New("truncated index"))
+	}
+	if count > maxSectionSize {
+		return nil, 0, errors.New("index is too large")
+	}
+
+	var entries []indexEntry
+	var offset, pos int64
+	for i := 0; i < int(count); i++ {
+		var fields [3]uint64
+		for j := range fields {
+			fields[j], err = binary.ReadUvarint(cr)
+			if err != nil {
+				return nil, 0, truncated(err, errors.New("truncated index"))
+			}
+		}
+
+		delta, size, dist := fields[0], fields[1], fields[2]
+		if delta == 0 || delta > maxSectionSize || size == 0 || size > maxSectionSize || dist > uint64(i)+1 {
+			return nil, 0, fmt.Errorf("invalid index entry %d", i)
+		}
+
+		offset += int64(delta)
+		e := indexEntry{offset: offset, pos: pos, size: int64(size), table: i - int(dist)}
+		if entries = append(entries, e); e.table >= 0 && entries[e.table].table != e.table {
+			return nil, 0, fmt.Errorf("invalid index entry %d", i)
+		}
+		pos += e.size
+	}
+
+	return entries, cr.n, nil
+}
+
+// skipIndex reads past the index and footer following a trailer, checking
+// that they are consistent.
+func skipIndex(r *bufio.Reader) error {
+	_, n, err := readIndex(r)
+	if err != nil {
+		return err
+	}
+
+	footer, _, err := readSection(r, indexFooterSize)
+	if err != nil {
+		return truncated(err, errors.New("truncated index footer"))
+	}
+
+	return checkIndexFooter(footer, n)
+}
+
+// checkIndexFooter checks the magic of footer and, unless want is
+// negative, the index length it stores.
+func checkIndexFooter(footer []byte, want int64) error {
+	if !bytes.Equal(footer[8:], indexMagic) {
+		return errors.New("invalid index footer")
+	}
+	if n := binary.BigEndian.Uint64(footer[:8]); want >= 0 && n != uint64(want) {
+		return fmt.Errorf("index length got %d, want %d", n, want)
+	}
+
+	return nil
+}
+
+// decompressIndexed decodes data holding a single stream written with an
+// index on up to o.concurrency goroutines. The blocks are cut out of data
+// at the offsets of the index, so they are parsed in parallel as well as
+// decoded. ok is false when data is not such a stream or does not match
+// its index, it is then left to the Reader.
+func decompressIndexed(data []byte, o options) (decompressed []byte, ok bool, err error) {
+	size := int64(len(data))
+	s, err := OpenSeekable(bytes.NewReader(data), size, WithDictionary(o.dict))
+	if err != nil {
+		return nil, false, nil
+	}
+
+	h := s.dec.h
+	trailerSize := int64(h.checksum().size()) + 8
+	indexLen := int64(binary.BigEndian.Uint64(data[size-indexFooterSize:]))
+	endPos := size - indexFooterSize - indexLen - trailerSize - 1
+	if data[endPos] != byte(blockEnd) {
+		return nil, false, nil
+	}
+
+	starts := make([]int64, 0, len(s.entries)+1)
+	for _, e := range s.entries {
+		starts = append(starts, e.offset)
+	}
+	starts = append(starts, endPos)
+	if starts[0] != int64(h.size()) {
+		return nil, false, nil
+	}
+
+	blocks := make([]block, len(s.entries))
+	err = parallel(len(blocks), o.concurrency, func(i int) error {
+		if starts[i] >= starts[i+1] {
+			return errors.New("index is out of order")
+		}
+		r := bufio.NewReader(bytes.NewReader(data[starts[i]:starts[i+1]]))
+		b, err := readBlock(r)
+		if err != nil {
+			return err
+		}
+		if _, err := r.ReadByte(); err != io.EOF || b.typ == blockEnd {
+			return fmt.Errorf("block %d does not match the index", i)
+		}
+		blocks[i] = b
+		return nil
+	})
+	if err != nil {
+		return nil, false, nil
+	}
+
+	decoded, err := s.dec.decodeAll(blocks, o.concurrency)
+	if err != nil {
+		return nil, true, err
+	}
+
+	digest, err := newDigest(h.checksum())
+	if err != nil {
+		return nil, true, err
+	}
+	for i, part := range decoded {
+		if int64(len(part)) != s.entries[i].size {
+			return nil, true, fmt.Errorf("block %d does not match the index", i)
+		}
+		digest.Write(part)
+	}
+	decompressed = make([]byte, 0, s.length)
+	for _, part := range decoded {
+		decompressed = append(decompressed, part...)
+	}
+	if err := digest.verify(data[endPos+1 : endPos+1+trailerSize]); err != nil {
+		return nil, true, err
+	}
+
+	return decompressed, true, nil
+}
+
+// Seekable gives random access to a stream written with WithIndex. Only the
+// blocks covering the requested range are read and decoded, so the
+// checksum of the whole stream is not verified.
+type Seekable struct {
+	r       io.ReaderAt
+	size    int64
+	dec     blockDecoder
+	entries []indexEntry
+	length  int64
+	offset  int64
+
+	// dict is the model of the dictionary of the stream, if any.
+	dict Model
+
+	// mu guards the cache, so ReadAt can be called concurrently.
+	mu      sync.Mutex
+	tables  map[int]Model
+	cached  int
+	decoded []byte
+}
+
+// OpenSeekable reads the header and the index of the stream stored in the
+// size bytes of r. The stream must be the only one in r, WithDictionary is
+// the only option it uses.
+func OpenSeekable(r io.ReaderAt, size int64, opts ...Option) (*Seekable, error) {
+	h, err := readStreamHeader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	if h.flags&flagIndex == 0 {
+		return nil, ErrNoIndex
+	}
+	dec, err := newBlockDecoder(h, newOptions(opts).dict)
+	if err != nil {
+		return nil, err
+	}
+
+	hdrSize := int64(h.size())
+	trailerSize := int64(h.checksum().size()) + 8
+	if size < hdrSize+1+trailerSize+indexFooterSize {
+		return nil, errors.New("truncated index footer")
+	}
+	footer := make([]byte, indexFooterSize)
+	if _, err := r.ReadAt(footer, size-indexFooterSize); err != nil {
+		return nil, err
+	}
+	if err := checkIndexFooter(footer, -1); err != nil {
+		return nil, err
+	}
+
+	indexLen := binary.BigEndian.Uint64(footer[:8])
+	if indexLen > uint64(size-hdrSize-1-trailerSize-indexFooterSize) {
+		return nil, fmt.Errorf("index length is too large: %d", indexLen)
+	}
+	indexStart := size - indexFooterSize - int64(indexLen)
+	index := make([]byte, indexLen+8)
+	if _, err := r.ReadAt(index, indexStart-8); err != nil {
+		return nil, err
+	}
+
+	entries, n, err := readIndex(bytes.NewReader(index[8:]))
+	if err != nil {
+		return nil, err
+	}
+	if n != int64(indexLen) {
+		return nil, fmt.Errorf("index length got %d, want %d", indexLen, n)
+	}
+
+	s := &Seekable{
+		r:       r,
+		size:    size,
+		dec:     dec,
+		entries: entries,
+		dict:    dec.model,
+		tables:  make(map[int]Model),
+		cached:  -1,
+	}
+	if len(entries) > 0 {
+		if entries[0].offset < hdrSize {
+			return nil, errors.New("invalid index entry 0")
+		}
+		last := entries[len(entries)-1]
+		if last.offset >= indexStart-trailerSize {
+			return nil, fmt.Errorf("invalid index entry %d", len(entries)-1)
+		}
+		s.length = last.pos + last.size
+	}
+	if length := binary.BigEndian.Uint64(index[:8]); length != uint64(s.length) {
+		return nil, fmt.Errorf("index covers %d bytes, trailer length is %d", s.length, length)
+	}
+
+	return s, nil
+}
+
+// Size returns the length of the decompressed data.
+func (s *Seekable) Size() int64 {
+	return s.length
+}
+
+// ReadAt decodes the blocks overlapping len(p) bytes at off.
+func (s *Seekable) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	n := 0
+	for n < len(p) && off < s.length {
+		i := sort.Search(len(s.entries), func(i int) bool {
+			e := s.entries[i]
+			return e.pos+e.size > off
+		})
+
+		data, err := s.decodeBlock(i)
+		if err != nil {
+			return n, err
+		}
+
+		m := copy(p[n:], data[off-s.entries[i].pos:])
+		n += m
+		off += int64(m)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// decodeBlock returns the decoded data of entry i, keeping the last block
+// decoded for sequential reads.
+func (s *Seekable) decodeBlock(i int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached == i {
+		return s.decoded, nil
+	}
+
+	e := s.entries[i]
+	b, err := s.readBlock(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var table Model
+	switch {
+	case b.typ == blockStored:
+	case s.dec.h.method != MethodHuffman:
+		if b.typ != s.dec.h.method.blockType() {
+			return nil, fmt.Errorf("block %d does not match the index", i)
+		}
+	default:
+		want := blockNewTable
+		if e.table != i {
+			want = blockReuseTable
+		}
+		if b.typ != want {
+			return nil, fmt.Errorf("block %d does not match the index", i)
+		}
+
+		if table, err = s.table(e.table); err != nil {
+			return nil, err
+		}
+	}
+
+	decoded, err := decodePayload(b, table, s.dec.h)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(decoded)) != e.size {
+		return nil, fmt.Errorf("block %d does not match the index", i)
+	}
+
+	s.cached = i
+	s.decoded = decoded
+
+	return decoded, nil
+}
+
+// table returns the table stored by the block of entry i, or the table of
+// the dictionary for -1.
+func (s *Seekable) table(i int) (Model, error) {
+	if i < 0 {
+		if s.dict == nil {
+			return nil, errors.New("index refers to a dictionary the stream does not use")
+		}
+		return s.dict, nil
+	}
+	if table, ok := s.tables[i]; ok {
+		return table, nil
+	}
+
+	b, err := s.readBlock(s.entries[i])
+	if err != nil {
+		return nil, err
+	}
+	if b.typ != blockNewTable {
+		return nil, fmt.Errorf("block %d does not match the index", i)
+	}
+
+	table, err := s.dec.modelFor(b)
+	if err != nil {
+		return nil, err
+	}
+	s.tables[i] = table
+
+	return table, nil
+}
+
+func (s *Seekable) readBlock(e indexEntry) (block, error) {
+	return readBlock(bufio.NewReader(io.NewSectionReader(s.r, e.offset, s.size-e.offset)))
+}
+
+// Read reads from the position set by Seek.
+func (s *Seekable) Read(p []byte) (int, error) {
+	if s.offset >= s.length {
+		return 0, io.EOF
+	}
+
+	n, err := s.ReadAt(p, s.offset)
+	s.offset += int64(n)
+	if err == io.EOF {
+		err = nil
+	}
+
+	return n, err
+}
+
+// Seek sets the position of the next Read in the decompressed data.
+func (s *Seekable) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.length
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	s.offset = offset
+
+	return offset, nil
+}
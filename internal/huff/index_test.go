@@ -0,0 +1,229 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+func compressIndexed(t *testing.T, data []byte, opts ...Option) []byte {
+	t.Helper()
+
+	compressed, err := Compress(data, append(opts, WithIndex())...)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return compressed
+}
+
+func TestIndexedDecompress(t *testing.T) {
+	first := bytes.Repeat([]byte("ééé abc "), 3000)
+	second := []byte("second stream")
+
+	stream := compressIndexed(t, first, WithBlockSize(1000))
+	plain, err := Compress(second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decompressed, err := Decompress(append(stream, plain...))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, append(first, second...))
+
+	decompressed, err = Decompress(append(plain, compressIndexed(t, nil)...), WithConcurrency(3))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, second)
+}
+
+func TestDecompressWithIndexInParallel(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	dict, err := TrainDictionary(0, [][]byte{data[:10000]}, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	stream := compressIndexed(t, data, WithBlockSize(10000))
+	plain, err := Compress(data, WithBlockSize(10000))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name   string
+		input  []byte
+		opts   []Option
+		output []byte
+		// indexed tells whether the index is used to split the blocks.
+		indexed bool
+	}{
+		{"Indexed stream", stream, nil, data, true},
+		{"Empty stream", compressIndexed(t, nil), nil, nil, true},
+		{"Dictionary", compressIndexed(t, data[:50000], WithDictionary(dict), WithBlockSize(5000)), []Option{WithDictionary(dict)}, data[:50000], true},
+		{"Other method", compressIndexed(t, data[:50000], WithMethod(MethodLZ77), WithBlockSize(5000)), nil, data[:50000], true},
+		{"Concatenated streams", append(compressIndexed(t, data[:3000], WithBlockSize(1000)), compressIndexed(t, data[3000:9000], WithBlockSize(1000))...), nil, data[:9000], false},
+		{"Without index", plain, nil, data, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithConcurrency(4)}, tt.opts...)
+			_, ok, err := decompressIndexed(tt.input, newOptions(opts))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			assertEqual(t, ok, tt.indexed)
+
+			decompressed, err := Decompress(tt.input, opts...)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			assertEqualBytes(t, decompressed, tt.output)
+		})
+	}
+
+	corrupted := append([]byte(nil), stream...)
+	corrupted[len(corrupted)/2] ^= 0x01
+	if _, err := Decompress(corrupted, WithConcurrency(4)); err == nil {
+		t.Error("Decompress() accepted a corrupted block")
+	}
+}
+
+func TestSeekableReadAt(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	compressed := compressIndexed(t, data, WithBlockSize(10000), WithConcurrency(4))
+	s, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, s.Size(), int64(len(data)))
+
+	tests := []struct {
+		name string
+		off  int64
+		n    int
+	}{
+		{name: "Start", off: 0, n: 10},
+		{name: "Within block", off: 12345, n: 100},
+		{name: "Across blocks", off: 9990, n: 25000},
+		{name: "End", off: int64(len(data)) - 7, n: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := make([]byte, tt.n)
+			n, err := s.ReadAt(p, tt.off)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			assertEqual(t, n, tt.n)
+			assertEqualBytes(t, p, data[tt.off:tt.off+int64(tt.n)])
+		})
+	}
+
+	p := make([]byte, 20)
+	n, err := s.ReadAt(p, int64(len(data))-5)
+	assertEqual(t, n, 5)
+	assertEqual(t, err, io.EOF)
+
+	// concurrent reads share the block cache
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			p := make([]byte, 3000)
+			if _, err := s.ReadAt(p, off); err != nil || !bytes.Equal(p, data[off:off+3000]) {
+				t.Errorf("concurrent read at %d failed: %v", off, err)
+			}
+		}(int64(i) * 40000)
+	}
+	wg.Wait()
+}
+
+func TestSeekableSeekRead(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 500)
+	compressed := compressIndexed(t, data, WithBlockSize(64))
+
+	s, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := s.Seek(-105, io.SeekEnd); err != nil {
+		t.Fatal(err.Error())
+	}
+	rest, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, rest, data[len(data)-105:])
+
+	pos, err := s.Seek(-1000, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, pos, int64(len(data)-1000))
+
+	_, err = s.Seek(-1, io.SeekStart)
+	assertEqual(t, err.Error(), "negative position")
+}
+
+func TestOpenSeekableErrors(t *testing.T) {
+	data := []byte("some data to compress")
+	indexed := compressIndexed(t, data, WithBlockSize(8))
+	plain, err := Compress(data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	badMagic := append([]byte{}, indexed...)
+	badMagic[len(badMagic)-1] = 'X'
+
+	// the trailer length ends right before the index
+	indexLen := int(binary.BigEndian.Uint64(indexed[len(indexed)-indexFooterSize:]))
+	badLength := append([]byte{}, indexed...)
+	badLength[len(badLength)-indexFooterSize-indexLen-1]++
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"No index", plain, ErrNoIndex.Error()},
+		{"Short", indexed[:5], "data is too short"},
+		{"Bad footer", badMagic, "invalid index footer"},
+		{"Bad length", badLength, "index covers 21 bytes, trailer length is 22"},
+		{"Truncated", indexed[:len(indexed)-1], "invalid index footer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenSeekable(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			assertEqual(t, err.Error(), tt.expected)
+		})
+	}
+
+	_, err = OpenSeekable(bytes.NewReader(plain), int64(len(plain)))
+	if !errors.Is(err, ErrNoIndex) {
+		t.Fatalf("got error %v, want %v", err, ErrNoIndex)
+	}
+}
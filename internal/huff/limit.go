@@ -0,0 +1,138 @@
+package huff
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const maxCodeLength = 63
+
+// errLimitTooSmall reports an alphabet with more symbols than codes of the
+// max code length can tell apart, the encoder stores such blocks.
+var errLimitTooSmall = errors.New("max code length too small for alphabet")
+
+// pmItem is either a leaf symbol or a package of two items from the level
+// below, as built by the package-merge algorithm.
+type pmItem struct {
+	weight int
+	char   rune
+	leaf   bool
+	left   *pmItem
+	right  *pmItem
+}
+
+// limitCodeLengths returns code lengths for freqMap that never exceed
+// maxLen. The Huffman lengths are kept when they already fit, otherwise the
+// optimal limited lengths are computed with package-merge.
+func limitCodeLengths(freqMap map[rune]int, maxLen int) (map[rune]int, error) {
+	if maxLen < 1 || maxLen > maxCodeLength {
+		return nil, errors.New("max code length out of range")
+	}
+	if maxLen < 31 && len(freqMap) > 1<<maxLen {
+		return nil, errLimitTooSmall
+	}
+
+	root, _, err := buildTree(freqMap)
+	if err != nil {
+		return nil, err
+	}
+
+	lengths := codeLengths(root)
+	for _, length := range lengths {
+		if length > maxLen {
+			return packageMerge(freqMap, maxLen), nil
+		}
+	}
+
+	return lengths, nil
+}
+
+// packageMerge implements the package-merge algorithm: the leaves are merged
+// with packages of the level below maxLen-1 times, and the code length of a
+// symbol is the number of times it is used by the 2n-2 cheapest items.
+func packageMerge(freqMap map[rune]int, maxLen int) map[rune]int {
+	leaves := make([]*pmItem, 0, len(freqMap))
+	for char, freq := range freqMap {
+		leaves = append(leaves, &pmItem{weight: freq, char: char, leaf: true})
+	}
+	slices.SortFunc(leaves, comparePMItems)
+
+	level := leaves
+	for i := 1; i < maxLen; i++ {
+		packages := make([]*pmItem, 0, len(level)/2)
+		for j := 0; j+1 < len(level); j += 2 {
+			packages = append(packages, &pmItem{
+				weight: level[j].weight + level[j+1].weight,
+				left:   level[j],
+				right:  level[j+1],
+			})
+		}
+		level = mergePMItems(leaves, packages)
+	}
+
+	lengths := make(map[rune]int, len(freqMap))
+	for _, item := range level[:2*len(leaves)-2] {
+		countLeaves(item, lengths)
+	}
+
+	return lengths
+}
+
+// checkMaxLength rejects lengths longer than the limit stored in the header.
+func checkMaxLength(lengths map[rune]int, maxLen int) error {
+	if maxLen == 0 {
+		return nil
+	}
+
+	for _, length := range lengths {
+		if length > maxLen {
+			return fmt.Errorf("code length %d exceeds max code length %d", length, maxLen)
+		}
+	}
+
+	return nil
+}
+
+func comparePMItems(a, b *pmItem) int {
+	if c := cmp.Compare(a.weight, b.weight); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.char, b.char)
+}
+
+// mergePMItems merges two weight ordered lists, leaves go first on ties so
+// the result does not depend on how packages were formed.
+func mergePMItems(leaves, packages []*pmItem) []*pmItem {
+	merged := make([]*pmItem, 0, len(leaves)+len(packages))
+
+	i, j := 0, 0
+	for i < len(leaves) && j < len(packages) {
+		if leaves[i].weight <= packages[j].weight {
+			merged = append(merged, leaves[i])
+			i++
+		} else {
+			merged = append(merged, packages[j])
+			j++
+		}
+	}
+	merged = append(merged, leaves[i:]...)
+	merged = append(merged, packages[j:]...)
+
+	return merged
+}
+
+func countLeaves(item *pmItem, lengths map[rune]int) {
+	if item == nil {
+		return
+	}
+
+	if item.leaf {
+		lengths[item.char]++
+		return
+	}
+
+	countLeaves(item.left, lengths)
+	countLeaves(item.right, lengths)
+}
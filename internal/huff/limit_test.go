@@ -0,0 +1,165 @@
+package huff
+
+import (
+	"bytes"
+	"testing"
+)
+
+func fibonacciFrequencies(n int) map[rune]int {
+	freqMap := make(map[rune]int, n)
+	a, b := 1, 1
+	for i := 0; i < n; i++ {
+		freqMap['a'+rune(i)] = a
+		a, b = b, a+b
+	}
+
+	return freqMap
+}
+
+func lengthsCost(freqMap map[rune]int, lengths map[rune]int) int {
+	cost, _ := codedSize(freqMap, lengths)
+	return cost
+}
+
+func TestPackageMerge(t *testing.T) {
+	freqMap := map[rune]int{'a': 1, 'b': 1, 'c': 2, 'd': 4, 'e': 8}
+
+	lengths, err := limitCodeLengths(freqMap, 3)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := map[rune]int{'a': 3, 'b': 3, 'c': 3, 'd': 3, 'e': 1}
+	assertEqual(t, printSortedMap(lengths), printSortedMap(expected))
+	assertEqual(t, lengthsCost(freqMap, lengths), 32)
+}
+
+func TestLimitCodeLengthsFibonacci(t *testing.T) {
+	freqMap := fibonacciFrequencies(30)
+
+	root, _, err := buildTree(freqMap)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	unlimited := codeLengths(root)
+	assertEqual(t, unlimited['a'], 29)
+
+	prevCost := lengthsCost(freqMap, unlimited)
+	for _, maxLen := range []int{29, 24, 15, 11, 5} {
+		lengths, err := limitCodeLengths(freqMap, maxLen)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if err := validateLengths(lengths); err != nil {
+			t.Fatalf("max length %d: %v", maxLen, err)
+		}
+		if err := checkMaxLength(lengths, maxLen); err != nil {
+			t.Fatalf("max length %d: %v", maxLen, err)
+		}
+		assertEqual(t, len(lengths), len(freqMap))
+
+		cost := lengthsCost(freqMap, lengths)
+		if cost < prevCost {
+			t.Errorf("max length %d: cost %d is lower than the looser limit cost %d", maxLen, cost, prevCost)
+		}
+		prevCost = cost
+	}
+}
+
+func TestLimitCodeLengthsInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		freqMap     map[rune]int
+		maxLen      int
+		expectedErr string
+	}{
+		{"Zero limit", map[rune]int{'a': 1, 'b': 1}, 0, "max code length out of range"},
+		{"Limit too long", map[rune]int{'a': 1, 'b': 1}, 64, "max code length out of range"},
+		{"Alphabet too large", map[rune]int{'a': 1, 'b': 1, 'c': 1, 'd': 1, 'e': 1}, 2, "max code length too small for alphabet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := limitCodeLengths(tt.freqMap, tt.maxLen)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("limitCodeLengths() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCompressMaxCodeLength(t *testing.T) {
+	var input bytes.Buffer
+	for char, freq := range fibonacciFrequencies(20) {
+		input.Write(bytes.Repeat([]byte{byte(char)}, freq))
+	}
+
+	compressed, err := Compress(input.Bytes(), WithMaxCodeLength(11))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	h, err := readHeader(compressed)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, h.maxCodeLen, 11)
+
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, input.Bytes())
+}
+
+func TestCompressLimitTooSmallForAlphabet(t *testing.T) {
+	var runes bytes.Buffer
+	for i := 0; i < 3000; i++ {
+		runes.WriteRune(0x4E00 + rune(i))
+	}
+	// every byte value, with runs long enough for run symbols
+	binary := make([]byte, 0, 4096)
+	for i := 0; i < 512; i++ {
+		binary = append(binary, bytes.Repeat([]byte{byte(i * 7)}, 1+i%8)...)
+	}
+	bytesLimit := []Option{WithAlphabet(AlphabetBytes), WithMaxCodeLength(8)}
+
+	tests := []struct {
+		name  string
+		input []byte
+		opts  []Option
+	}{
+		{"Runes", runes.Bytes(), []Option{WithMaxCodeLength(11)}},
+		{"Run length", binary, append(bytesLimit, WithRunLength())},
+		{"LZ77", binary, append(bytesLimit, WithMethod(MethodLZ77))},
+		{"BWT", runes.Bytes(), []Option{WithMaxCodeLength(4), WithMethod(MethodBWT)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := Compress(tt.input, tt.opts...)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			h, err := readHeader(compressed)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			assertEqual(t, blockType(compressed[h.size()]), blockStored)
+
+			assertRoundTrip(t, tt.input, tt.opts)
+		})
+	}
+}
+
+func TestDecompressCodeLengthOverLimit(t *testing.T) {
+	input := []byte{'H', 'U', 'F', 0x1A, 3, 0, 2, 0, 0,
+		1, 4, 97, 2, 1, 3, 3, 0}
+
+	_, err := Decompress(input)
+	expectedErr := "code length 3 exceeds max code length 2"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Decompress() error got=%v, want=%v", err, expectedErr)
+	}
+}
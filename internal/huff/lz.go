@@ -0,0 +1,258 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"compression_tool.nobletk/internal/bitio"
+	"compression_tool.nobletk/internal/lz77"
+)
+
+// defaultWindowSize is how far back LZ77 matches reach when WithWindowSize
+// is not given.
+const defaultWindowSize = 32 << 10
+
+// Literals and match lengths share one alphabet numbered as in DEFLATE:
+// symbols below 256 are bytes, 257 to 285 are length codes followed by
+// extra bits. 256 is not used.
+const (
+	firstLengthSymbol = 257
+	numLengthSymbols  = 29
+)
+
+// lengthBases and lengthExtra hold the shortest length and the number of
+// extra bits of every length code.
+var lengthBases, lengthExtra = func() ([numLengthSymbols]int, [numLengthSymbols]uint8) {
+	var bases [numLengthSymbols]int
+	var extra [numLengthSymbols]uint8
+
+	base := lz77.MinMatch
+	for i := 0; i < numLengthSymbols-1; i++ {
+		if i >= 8 {
+			extra[i] = uint8(i/4 - 1)
+		}
+		bases[i] = base
+		base += 1 << extra[i]
+	}
+	bases[numLengthSymbols-1] = lz77.MaxMatch
+
+	return bases, extra
+}()
+
+// lengthSymbol returns the code of a match length and its extra bits.
+func lengthSymbol(length int) (rune, uint8, uint64) {
+	i := numLengthSymbols - 1
+	for lengthBases[i] > length {
+		i--
+	}
+
+	return rune(firstLengthSymbol + i), lengthExtra[i], uint64(length - lengthBases[i])
+}
+
+// lengthBase returns the shortest length of a length code and the number
+// of extra bits that follow it.
+func lengthBase(sym rune) (int, uint8, bool) {
+	i := int(sym) - firstLengthSymbol
+	if i < 0 || i >= numLengthSymbols {
+		return 0, 0, false
+	}
+
+	return lengthBases[i], lengthExtra[i], true
+}
+
+// distanceSymbol returns the code of a match distance and its extra bits.
+// Codes 0 to 3 are distances 1 to 4, then every power of two is split in
+// two codes, which matches DEFLATE up to its 32 KiB window.
+func distanceSymbol(dist int) (rune, uint8, uint64) {
+	x := uint64(dist - 1)
+	if x < 4 {
+		return rune(x), 0, 0
+	}
+
+	n := uint8(bits.Len64(x) - 2)
+	sym := 2*rune(n) + 2 + rune(x>>n&1)
+
+	return sym, n, x & (1<<n - 1)
+}
+
+// distanceBase returns the shortest distance of a distance code and the
+// number of extra bits that follow it.
+func distanceBase(sym rune) (int, uint8, bool) {
+	if sym < 0 || sym >= 48 {
+		return 0, 0, false
+	}
+	if sym < 4 {
+		return int(sym) + 1, 0, true
+	}
+
+	n := uint8(sym/2 - 1)
+
+	return (2+int(sym&1))<<n + 1, n, true
+}
+
+func init() {
+	RegisterCoder(MethodLZ77, lz77Coder{})
+}
+
+// lz77Model holds the literal and length table and the distance table,
+// with the tokens of the block when encoding and as decode tables when
+// decoding.
+type lz77Model struct {
+	tokens      []lz77.Token
+	litLengths  map[rune]int
+	distLengths map[rune]int
+	litTable    *decodeTable
+	distTable   *decodeTable
+}
+
+// lz77Coder replaces repeated strings with matches and codes the literals
+// and lengths with one Huffman table and the distances with another.
+type lz77Coder struct{}
+
+func (lz77Coder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	window := cfg.Window
+	if window == 0 {
+		window = defaultWindowSize
+	}
+	tokens := lz77.Parse(data, window)
+
+	litFreq := make(FrequencyMap)
+	distFreq := make(FrequencyMap)
+	for _, t := range tokens {
+		if t.Length == 0 {
+			litFreq[rune(t.Literal)]++
+			continue
+		}
+		sym, _, _ := lengthSymbol(t.Length)
+		litFreq[sym]++
+		sym, _, _ = distanceSymbol(t.Distance)
+		distFreq[sym]++
+	}
+
+	litLengths, err := buildLengths(litFreq, cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+	distLengths, err := buildLengths(distFreq, cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lz77Model{tokens: tokens, litLengths: litLengths, distLengths: distLengths}, nil
+}
+
+// WriteModel stores the literal and length table after its length, then
+// the distance table.
+func (lz77Coder) WriteModel(m Model) []byte {
+	lm := m.(*lz77Model)
+	table := serializeLengths(lm.litLengths)
+
+	b := binary.AppendUvarint(nil, uint64(len(table)))
+	b = append(b, table...)
+
+	return append(b, serializeLengths(lm.distLengths)...)
+}
+
+// Encode codes the tokens parsed by BuildModel, data is not read again.
+func (lz77Coder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	lm := m.(*lz77Model)
+	litCodes := canonicalCodes(lm.litLengths)
+	distCodes := canonicalCodes(lm.distLengths)
+
+	var bitBuff bytes.Buffer
+	bw := bitio.NewBitWriter(&bitBuff)
+	for _, t := range lm.tokens {
+		if t.Length == 0 {
+			c := litCodes[rune(t.Literal)]
+			bw.WriteBits(c.bits, c.length)
+			continue
+		}
+
+		sym, n, extra := lengthSymbol(t.Length)
+		c := litCodes[sym]
+		bw.WriteBits(c.bits, c.length)
+		bw.WriteBits(extra, n)
+
+		sym, n, extra = distanceSymbol(t.Distance)
+		c = distCodes[sym]
+		bw.WriteBits(c.bits, c.length)
+		bw.WriteBits(extra, n)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, err
+	}
+
+	return bitBuff.Bytes(), int(bw.BitsWritten()), nil
+}
+
+func (lz77Coder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	tableLen, n := binary.Uvarint(b)
+	if n <= 0 || tableLen > uint64(len(b)-n) {
+		return nil, errors.New("invalid literal table length")
+	}
+	b = b[n:]
+
+	litTable, err := parseDecodeTable(b[:tableLen], cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+	distTable, err := parseDecodeTable(b[tableLen:], cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lz77Model{litTable: litTable, distTable: distTable}, nil
+}
+
+func (lz77Coder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	lm := m.(*lz77Model)
+
+	r := bitio.NewBitReader(payload)
+	decoded := make([]byte, 0, len(payload)*3)
+	for r.BitsRead() < int64(totalBits) {
+		if lm.litTable == nil {
+			return nil, errors.New("payload without code lengths")
+		}
+		sym, err := lm.litTable.readSymbol(r)
+		if err != nil {
+			return nil, err
+		}
+		if sym < 256 {
+			decoded = append(decoded, byte(sym))
+			continue
+		}
+
+		length, n, ok := lengthBase(sym)
+		if !ok {
+			return nil, fmt.Errorf("invalid length symbol: %d", sym)
+		}
+		length += int(r.ReadBits(n))
+
+		if lm.distTable == nil {
+			return nil, errors.New("match without distance code lengths")
+		}
+		sym, err = lm.distTable.readSymbol(r)
+		if err != nil {
+			return nil, err
+		}
+		dist, n, ok := distanceBase(sym)
+		if !ok {
+			return nil, fmt.Errorf("invalid distance symbol: %d", sym)
+		}
+		dist += int(r.ReadBits(n))
+
+		decoded, err = lz77.AppendMatch(decoded, length, dist)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.BitsRead() > int64(totalBits) {
+		return nil, errors.New("last code exceeds payload bit count")
+	}
+
+	return decoded, nil
+}
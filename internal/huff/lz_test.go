@@ -0,0 +1,138 @@
+package huff
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLengthSymbols(t *testing.T) {
+	for length := 3; length <= 258; length++ {
+		sym, n, extra := lengthSymbol(length)
+		base, baseN, ok := lengthBase(sym)
+		if !ok || baseN != n || base+int(extra) != length || extra >= 1<<n {
+			t.Fatalf("length %d got symbol %d, base %d, extra %d bits %d", length, sym, base, n, extra)
+		}
+	}
+
+	// spot checks against the DEFLATE length table
+	tests := []struct {
+		length int
+		sym    rune
+		n      uint8
+	}{
+		{3, 257, 0},
+		{10, 264, 0},
+		{11, 265, 1},
+		{19, 269, 2},
+		{227, 284, 5},
+		{257, 284, 5},
+		{258, 285, 0},
+	}
+	for _, tt := range tests {
+		sym, n, _ := lengthSymbol(tt.length)
+		assertEqual(t, sym, tt.sym)
+		assertEqual(t, n, tt.n)
+	}
+}
+
+func TestDistanceSymbols(t *testing.T) {
+	for _, dist := range []int{1, 2, 4, 5, 6, 7, 8, 9, 100, 1024, 24576, 24577, 32768, 1 << 20, 1<<24 - 1, 1 << 24} {
+		sym, n, extra := distanceSymbol(dist)
+		base, baseN, ok := distanceBase(sym)
+		if !ok || baseN != n || base+int(extra) != dist {
+			t.Fatalf("distance %d got symbol %d, base %d, extra %d bits %d", dist, sym, base, n, extra)
+		}
+	}
+
+	// spot checks against the DEFLATE distance table
+	tests := []struct {
+		dist int
+		sym  rune
+		n    uint8
+	}{
+		{1, 0, 0},
+		{4, 3, 0},
+		{5, 4, 1},
+		{7, 5, 1},
+		{25, 9, 3},
+		{24577, 29, 13},
+		{32768, 29, 13},
+	}
+	for _, tt := range tests {
+		sym, n, _ := distanceSymbol(tt.dist)
+		assertEqual(t, sym, tt.sym)
+		assertEqual(t, n, tt.n)
+	}
+}
+
+func TestLZ77RoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		opts  []Option
+	}{
+		{name: "No matches", input: []byte("abcdefgh")},
+		{name: "Small window", input: data[:200000], opts: []Option{WithWindowSize(1 << 8)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRoundTrip(t, tt.input, append([]Option{WithMethod(MethodLZ77)}, tt.opts...))
+		})
+	}
+}
+
+func TestLZ77BeatsHuffman(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	huffman, err := Compress(data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	lz, err := Compress(data, WithMethod(MethodLZ77))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(lz) >= len(huffman)*3/4 {
+		t.Errorf("LZ77 output got %d bytes, Huffman output %d", len(lz), len(huffman))
+	}
+}
+
+func TestDecodeLZ77InvalidPayload(t *testing.T) {
+	model, payload, totalBits := codeBlock(t, lz77Coder{}, []byte("abcabcabcabc"), CoderConfig{})
+	noDist := model[:1+model[0]]
+
+	tests := []struct {
+		name        string
+		model       []byte
+		payload     []byte
+		totalBits   int
+		expectedErr string
+	}{
+		{"No tables", []byte{0}, []byte{0}, 1, "payload without code lengths"},
+		{"Bad table length", []byte{9, 1}, payload, totalBits, "invalid literal table length"},
+		{"No distance table", noDist, payload, totalBits, "match without distance code lengths"},
+		{"Bit count too small", model, payload, totalBits - 1, "last code exceeds payload bit count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := lz77Coder{}.ReadModel(tt.model, CoderConfig{})
+			if err == nil {
+				_, err = lz77Coder{}.Decode(m, tt.payload, tt.totalBits, CoderConfig{})
+			}
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Decode() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package huff
+
+// Method selects how blocks are coded, it is kept in a header byte of its
+// own.
+type Method byte
+
+const (
+	// MethodHuffman codes every block with a static Huffman table stored in
+	// the stream or reused from an earlier block.
+	MethodHuffman Method = iota
+	// MethodAdaptive codes every block in one pass with an adaptive Huffman
+	// tree that the decoder rebuilds as it goes, so no table is stored.
+	MethodAdaptive
+	// MethodLZ77 replaces repeated strings with matches in a window of
+	// earlier bytes, then codes literals, lengths and distances with static
+	// Huffman tables stored in every block. It works on bytes whatever the
+	// alphabet.
+	MethodLZ77
+	// MethodBWT sorts every block with the Burrows-Wheeler transform, then
+	// applies move-to-front and zero run length coding and codes the
+	// result with a static Huffman table stored in the block. It works on
+	// bytes whatever the alphabet.
+	MethodBWT
+	// MethodContext codes every symbol with a static Huffman table chosen by
+	// the symbol before it. Contexts too rare to pay for a table of their
+	// own share an order-0 table.
+	MethodContext
+	// MethodRange codes every block with a range coder driven by the
+	// symbol frequencies of the block, which are stored in place of a
+	// table. It gets within a fraction of a bit of the entropy of skewed
+	// distributions, where Huffman codes lose up to a bit per symbol.
+	MethodRange
+	// MethodANS codes every block with a table based asymmetric numeral
+	// systems (tANS) coder, as zstd does, whose frequencies are stored
+	// scaled to the size of its state table. It gets close to the ratio of
+	// MethodRange at the speed of table lookups.
+	MethodANS
+	// MethodWords codes every block with a Huffman table whose symbols are
+	// the literals of the alphabet and the words and whitespace runs that
+	// recur in the block, stored in a dictionary before the table. Rare
+	// tokens are spelled out with literals.
+	MethodWords
+)
+
+// valid reports whether m can be read and written.
+func (m Method) valid() bool {
+	_, ok := coders[m]
+
+	return ok
+}
+
+// blockType returns the type of the blocks coded with m, other than stored
+// blocks. Huffman blocks may also reuse a table, the blocks of every other
+// Coder are coded blocks.
+func (m Method) blockType() blockType {
+	if m == MethodHuffman {
+		return blockNewTable
+	}
+
+	return blockCoded
+}
@@ -0,0 +1,182 @@
+package huff
+
+import (
+	"fmt"
+	"runtime"
+	"unicode/utf8"
+
+	"compression_tool.nobletk/internal/lz77"
+)
+
+// defaultBlockSize is the amount of input coded with one table when
+// WithBlockSize is not given.
+const defaultBlockSize = 128 << 10
+
+type Option func(*options)
+
+type options struct {
+	legacy   bool
+	checksum Checksum
+	alphabet Alphabet
+	// maxCodeLen limits the code lengths, zero leaves them unlimited.
+	maxCodeLen int
+	blockSize  int
+	// concurrency is the number of blocks coded at the same time.
+	concurrency int
+	index       bool
+	method      Method
+	window      int
+	// rle codes runs of a symbol with run symbols.
+	rle  bool
+	dict *Dictionary
+}
+
+func newOptions(opts []Option) options {
+	o := options{blockSize: defaultBlockSize, concurrency: 1, window: defaultWindowSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func (o options) validate() error {
+	if o.checksum > ChecksumSHA256 {
+		return fmt.Errorf("unknown checksum: %d", o.checksum)
+	}
+	if o.alphabet > AlphabetBytes {
+		return fmt.Errorf("unknown alphabet: %d", o.alphabet)
+	}
+	if o.maxCodeLen < 0 || o.maxCodeLen > maxCodeLength {
+		return fmt.Errorf("invalid max code length: %d", o.maxCodeLen)
+	}
+	if o.blockSize < utf8.UTFMax || o.blockSize > 1<<30 {
+		return fmt.Errorf("invalid block size: %d", o.blockSize)
+	}
+	if !o.method.valid() {
+		return fmt.Errorf("unknown method: %d", o.method)
+	}
+	if (o.method == MethodAdaptive || o.method == MethodRange || o.method == MethodANS) && o.maxCodeLen != 0 {
+		return fmt.Errorf("max code length is not supported by method %d", o.method)
+	}
+	if o.rle && o.method != MethodHuffman {
+		return fmt.Errorf("run-length coding is not supported by method %d", o.method)
+	}
+	if o.dict != nil && o.method != MethodHuffman {
+		return fmt.Errorf("dictionary is not supported by method %d", o.method)
+	}
+	if o.dict != nil {
+		if err := checkMaxLength(o.dict.lengths, o.maxCodeLen); err != nil {
+			return fmt.Errorf("dictionary: %w", err)
+		}
+	}
+	if err := lz77.ValidWindow(o.window); err != nil {
+		return err
+	}
+	if o.concurrency < 1 {
+		return fmt.Errorf("invalid concurrency: %d", o.concurrency)
+	}
+
+	return nil
+}
+
+func (o options) coderConfig() CoderConfig {
+	return CoderConfig{Alphabet: o.alphabet, MaxCodeLen: o.maxCodeLen, RunLength: o.rle, Window: o.window}
+}
+
+// WithLegacyFormat makes Decompress read the header-less format written
+// before the magic number was introduced.
+func WithLegacyFormat() Option {
+	return func(o *options) {
+		o.legacy = true
+	}
+}
+
+// WithChecksum selects the hash Compress stores in the trailer, CRC32 is
+// used when it is not given.
+func WithChecksum(c Checksum) Option {
+	return func(o *options) {
+		o.checksum = c
+	}
+}
+
+// WithAlphabet selects how Compress splits the input into symbols, runes
+// are used when it is not given.
+func WithAlphabet(a Alphabet) Option {
+	return func(o *options) {
+		o.alphabet = a
+	}
+}
+
+// WithMaxCodeLength limits every code to at most n bits, which bounds the
+// size of lookup tables needed by decoders.
+func WithMaxCodeLength(n int) Option {
+	return func(o *options) {
+		o.maxCodeLen = n
+	}
+}
+
+// WithBlockSize sets how many input bytes are coded with one table. Smaller
+// blocks follow shifts in the symbol distribution more closely at the cost
+// of storing more tables.
+func WithBlockSize(n int) Option {
+	return func(o *options) {
+		o.blockSize = n
+	}
+}
+
+// WithConcurrency codes up to n blocks at the same time, zero uses one
+// goroutine per CPU. The output does not depend on n, blocks are still
+// written in order and reuse tables exactly as they do when coded one by
+// one.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		if n == 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		o.concurrency = n
+	}
+}
+
+// WithIndex makes the Writer append an index of its blocks to the stream,
+// which OpenSeekable needs for random access.
+func WithIndex() Option {
+	return func(o *options) {
+		o.index = true
+	}
+}
+
+// WithMethod selects how blocks are coded, static Huffman tables are used
+// when it is not given.
+func WithMethod(m Method) Option {
+	return func(o *options) {
+		o.method = m
+	}
+}
+
+// WithWindowSize sets how many bytes back MethodLZ77 looks for matches, a
+// power of two from 256 bytes to 16 MiB. Matches never cross blocks.
+func WithWindowSize(n int) Option {
+	return func(o *options) {
+		o.window = n
+	}
+}
+
+// WithRunLength codes every run of a symbol as the symbol followed by its
+// length in run symbols before the Huffman table is built, which suits
+// padded or whitespace heavy input. Only MethodHuffman supports it.
+func WithRunLength() Option {
+	return func(o *options) {
+		o.rle = true
+	}
+}
+
+// WithDictionary makes Compress start out with the table of d, which
+// blocks reuse while it is smaller than a table of their own, and store
+// only the ID of d. Decompress, NewReader and OpenSeekable need the same
+// dictionary to read such a stream. Only MethodHuffman supports it.
+func WithDictionary(d *Dictionary) Option {
+	return func(o *options) {
+		o.dict = d
+	}
+}
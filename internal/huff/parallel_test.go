@@ -0,0 +1,92 @@
+package huff
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConcurrencyMatchesSerial(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "runes", opts: []Option{WithBlockSize(8 << 10)}},
+		{name: "bytes", opts: []Option{WithBlockSize(5000), WithAlphabet(AlphabetBytes)}},
+		{name: "limited", opts: []Option{WithBlockSize(3001), WithMaxCodeLength(9)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serial, err := Compress(data, tt.opts...)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			for _, n := range []int{2, 3, 8, 0} {
+				opts := append(tt.opts[:len(tt.opts):len(tt.opts)], WithConcurrency(n))
+
+				compressed, err := Compress(data, opts...)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				if !bytes.Equal(compressed, serial) {
+					t.Fatalf("concurrency %d output differs from serial output", n)
+				}
+
+				decompressed, err := Decompress(compressed, WithConcurrency(n))
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				if !bytes.Equal(decompressed, data) {
+					t.Fatalf("concurrency %d decompressed data differs from the original", n)
+				}
+			}
+		})
+	}
+}
+
+func TestConcurrentReaderErrors(t *testing.T) {
+	input := bytes.Repeat([]byte("abcdefgh"), 1000)
+	compressed, err := Compress(input, WithBlockSize(1000))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	corrupted := append([]byte{}, compressed...)
+	corrupted[len(corrupted)-9] ^= 0xFF
+
+	_, err = Decompress(corrupted, WithConcurrency(4))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("got error %v, want %v", err, ErrChecksumMismatch)
+	}
+
+	_, err = Decompress(compressed[:len(compressed)/2], WithConcurrency(4))
+	if err == nil {
+		t.Fatal("expected an error for truncated data")
+	}
+
+	_, err = NewReader(bytes.NewReader(compressed), WithConcurrency(-1))
+	assertEqual(t, err.Error(), "invalid concurrency: -1")
+}
+
+func TestParallel(t *testing.T) {
+	var calls atomic.Int32
+	err := parallel(20, 4, func(i int) error {
+		calls.Add(1)
+		if i == 7 || i == 13 {
+			return errors.New(string(rune('a' + i)))
+		}
+		return nil
+	})
+
+	assertEqual(t, int(calls.Load()), 20)
+	assertEqual(t, err.Error(), "h")
+}
@@ -0,0 +1,293 @@
+package huff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"sort"
+
+	"compression_tool.nobletk/internal/rangecoder"
+)
+
+// rangeTotal is the sum the frequencies of a range block are scaled down
+// to when they add up to more, it is raised for blocks with more symbols.
+const rangeTotal = 1 << 16
+
+// maxSymbolCount bounds the symbol count of a block, no block holds more
+// symbols than the largest block size allows.
+const maxSymbolCount = 1 << 30
+
+// rangeModel holds the symbols of a static model in ascending order with
+// their frequencies and cumulative frequencies. MethodANS uses it to number
+// the symbols too.
+type rangeModel struct {
+	symbols []rune
+	freqs   []uint32
+	starts  []uint32
+	total   uint32
+}
+
+func newRangeModel(freqMap FrequencyMap) rangeModel {
+	m := rangeModel{symbols: make([]rune, 0, len(freqMap))}
+	for char := range freqMap {
+		m.symbols = append(m.symbols, char)
+	}
+	slices.Sort(m.symbols)
+
+	for _, char := range m.symbols {
+		m.freqs = append(m.freqs, uint32(freqMap[char]))
+		m.starts = append(m.starts, m.total)
+		m.total += uint32(freqMap[char])
+	}
+
+	return m
+}
+
+// find returns the index of the symbol covering v.
+func (m rangeModel) find(v uint32) int {
+	return sort.Search(len(m.starts)-1, func(i int) bool { return m.starts[i+1] > v })
+}
+
+func init() {
+	RegisterCoder(MethodRange, rangeCoder{})
+}
+
+// rangeCodedModel holds the scaled frequencies of a block and its symbol
+// count, which tells the decoder where to stop.
+type rangeCodedModel struct {
+	freqMap FrequencyMap
+	count   int
+	m       rangeModel
+}
+
+// rangeCoder codes blocks with a range coder driven by the symbol
+// frequencies of the block.
+type rangeCoder struct{}
+
+func (rangeCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	freqMap, err := getSymbolsFrequency(data, cfg.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for _, freq := range freqMap {
+		count += freq
+	}
+
+	total := rangeTotal
+	for total < 2*len(freqMap) {
+		total <<= 1
+	}
+	freqMap = normalizeFrequencies(freqMap, total)
+
+	return &rangeCodedModel{freqMap: freqMap, count: count, m: newRangeModel(freqMap)}, nil
+}
+
+// WriteModel stores the symbol count followed by the frequencies.
+func (rangeCoder) WriteModel(m Model) []byte {
+	rm := m.(*rangeCodedModel)
+	b := binary.AppendUvarint(nil, uint64(rm.count))
+
+	return append(b, serializeFrequencies(rm.freqMap)...)
+}
+
+func (rangeCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	rm := m.(*rangeCodedModel)
+	if rm.count == 0 {
+		return nil, 0, nil
+	}
+
+	index := make(map[rune]int, len(rm.m.symbols))
+	for i, char := range rm.m.symbols {
+		index[char] = i
+	}
+
+	e := rangecoder.NewEncoder()
+	for i := 0; i < len(data); {
+		char, sz, err := cfg.Alphabet.next(data[i:])
+		if err != nil {
+			return nil, 0, err
+		}
+		i += sz
+
+		s := index[char]
+		e.Encode(rm.m.starts[s], rm.m.freqs[s], rm.m.total)
+	}
+	payload := e.Finish()
+
+	return payload, 8 * len(payload), nil
+}
+
+func (rangeCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	count, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, errors.New("invalid symbol count")
+	}
+	if count > maxSymbolCount {
+		return nil, errors.New("symbol count is too large")
+	}
+
+	freqMap, err := deserializeFrequencies(b[n:])
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 && len(freqMap) == 0 {
+		return nil, errors.New("symbols without frequencies")
+	}
+
+	return &rangeCodedModel{freqMap: freqMap, count: int(count), m: newRangeModel(freqMap)}, nil
+}
+
+func (rangeCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	rm := m.(*rangeCodedModel)
+	if rm.count == 0 {
+		return nil, nil
+	}
+
+	d, err := rangecoder.NewDecoder(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	decompressed := make([]byte, 0, min(rm.count, 8*len(payload)+1<<16))
+	for i := 0; i < rm.count; i++ {
+		s := rm.m.find(d.Freq(rm.m.total))
+		if err := d.Decode(rm.m.starts[s], rm.m.freqs[s]); err != nil {
+			return nil, err
+		}
+		if d.Overrun() {
+			return nil, errors.New("truncated range coder data")
+		}
+		decompressed = cfg.Alphabet.appendSymbol(decompressed, rm.m.symbols[s])
+	}
+
+	return decompressed, nil
+}
+
+// normalizeFrequencies scales freqMap down to add up to total when it
+// adds up to more, otherwise it returns freqMap itself.
+func normalizeFrequencies(freqMap FrequencyMap, total int) FrequencyMap {
+	sum := 0
+	for _, freq := range freqMap {
+		sum += freq
+	}
+	if sum <= total {
+		return freqMap
+	}
+
+	return scaleFrequencies(freqMap, total)
+}
+
+// scaleFrequencies scales freqMap to add up to total exactly, keeping
+// every symbol at one or more. total must be at least the number of
+// symbols.
+func scaleFrequencies(freqMap FrequencyMap, total int) FrequencyMap {
+	sum := 0
+	for _, freq := range freqMap {
+		sum += freq
+	}
+
+	symbols := make([]rune, 0, len(freqMap))
+	for char := range freqMap {
+		symbols = append(symbols, char)
+	}
+	// most frequent first, ties in symbol order so the result is stable
+	slices.SortFunc(symbols, func(a, b rune) int {
+		if freqMap[a] != freqMap[b] {
+			return freqMap[b] - freqMap[a]
+		}
+		return int(a - b)
+	})
+
+	scaled := make(FrequencyMap, len(freqMap))
+	left := total
+	for _, char := range symbols {
+		freq := max(1, int(uint64(freqMap[char])*uint64(total)/uint64(sum)))
+		scaled[char] = freq
+		left -= freq
+	}
+
+	// rounding up rare symbols may overshoot, the most frequent symbols
+	// give the difference back
+	for i := 0; left < 0; i = (i + 1) % len(symbols) {
+		char := symbols[i]
+		take := min(scaled[char]-1, -left, max(1, scaled[char]/8))
+		scaled[char] -= take
+		left += take
+	}
+	scaled[symbols[0]] += left
+
+	return scaled
+}
+
+// serializeFrequencies stores the frequencies like serializeLengths stores
+// code lengths, with every frequency as an unsigned varint.
+func serializeFrequencies(freqMap FrequencyMap) []byte {
+	symbols := make([]rune, 0, len(freqMap))
+	for char := range freqMap {
+		symbols = append(symbols, char)
+	}
+	slices.Sort(symbols)
+
+	var buff bytes.Buffer
+	next := rune(0)
+	for i := 0; i < len(symbols); {
+		j := i + 1
+		for j < len(symbols) && symbols[j] == symbols[j-1]+1 {
+			j++
+		}
+
+		buff.Write(binary.AppendUvarint(nil, uint64(symbols[i]-next)))
+		buff.Write(binary.AppendUvarint(nil, uint64(j-i)))
+		for _, char := range symbols[i:j] {
+			buff.Write(binary.AppendUvarint(nil, uint64(freqMap[char])))
+		}
+
+		next = symbols[j-1] + 1
+		i = j
+	}
+
+	return buff.Bytes()
+}
+
+func deserializeFrequencies(b []byte) (FrequencyMap, error) {
+	freqMap := make(FrequencyMap)
+
+	var next, total uint64
+	for len(b) > 0 {
+		gap, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("invalid symbol gap in frequencies")
+		}
+		b = b[n:]
+
+		count, n := binary.Uvarint(b)
+		if n <= 0 || count == 0 || count > uint64(len(b)) {
+			return nil, errors.New("invalid run size in frequencies")
+		}
+		b = b[n:]
+
+		next += gap
+		if next+count > 1<<31 {
+			return nil, errors.New("symbol out of range in frequencies")
+		}
+		for ; count > 0; count-- {
+			freq, n := binary.Uvarint(b)
+			if n <= 0 || freq == 0 {
+				return nil, errors.New("invalid frequency")
+			}
+			b = b[n:]
+
+			total += freq
+			if total > rangecoder.MaxTotal {
+				return nil, errors.New("frequencies add up to more than the coder supports")
+			}
+			freqMap[rune(next)] = int(freq)
+			next++
+		}
+	}
+
+	return freqMap, nil
+}
@@ -0,0 +1,136 @@
+package huff
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+// skewedText returns text drawn from the frequencies of TestValidBuildTree,
+// where 'e' takes 40% of the symbols.
+func skewedText(n int) []byte {
+	freqs := map[rune]int{'c': 32, 'd': 42, 'e': 120, 'k': 7, 'l': 42, 'm': 24, 'u': 37, 'z': 2}
+	var pool strings.Builder
+	for _, char := range "cdeklmuz" {
+		pool.WriteString(strings.Repeat(string(char), freqs[char]))
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = pool.String()[rng.Intn(pool.Len())]
+	}
+
+	return out
+}
+
+func TestRangeBeatsHuffman(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	binary := make([]byte, 100000)
+	rng := rand.New(rand.NewSource(2))
+	for i := range binary {
+		if rng.Intn(20) == 0 {
+			binary[i] = 'b'
+		} else {
+			binary[i] = 'a'
+		}
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		// ratio is the largest range output size accepted, in percent
+		// of the Huffman output size.
+		ratio int
+	}{
+		{"Skewed letters", skewedText(100000), 99},
+		{"Two symbols", binary, 40},
+		{"Test file", data, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			huffman, err := Compress(tt.input)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			ranged, err := Compress(tt.input, WithMethod(MethodRange))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if len(ranged)*100 >= len(huffman)*tt.ratio {
+				t.Errorf("range output got %d bytes, want less than %d%% of %d", len(ranged), tt.ratio, len(huffman))
+			}
+		})
+	}
+}
+
+func TestRangeDecodeOverrun(t *testing.T) {
+	model, payload, totalBits := codeBlock(t, rangeCoder{}, []byte("abcdefgh"), CoderConfig{})
+	_, n := binary.Uvarint(model)
+	model = append(binary.AppendUvarint(nil, maxSymbolCount), model[n:]...)
+
+	m, err := rangeCoder{}.ReadModel(model, CoderConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = rangeCoder{}.Decode(m, payload, totalBits, CoderConfig{})
+	if err == nil || err.Error() != "truncated range coder data" {
+		t.Errorf("Decode() error got=%v, want=truncated range coder data", err)
+	}
+}
+
+func TestNormalizeFrequencies(t *testing.T) {
+	freqMap := FrequencyMap{'a': 100000, 'b': 50000, 'c': 1, 'd': 1, 'e': 3}
+	scaled := normalizeFrequencies(freqMap, 1<<10)
+
+	sum := 0
+	for char, freq := range scaled {
+		if freq < 1 {
+			t.Errorf("frequency of %q got %d, want at least 1", char, freq)
+		}
+		sum += freq
+	}
+	assertEqual(t, sum, 1<<10)
+	assertEqual(t, len(scaled), len(freqMap))
+
+	small := FrequencyMap{'a': 3, 'b': 1}
+	assertEqual(t, normalizeFrequencies(small, 1<<10)['a'], 3)
+}
+
+func TestDeserializeFrequencies(t *testing.T) {
+	freqMap := FrequencyMap{'a': 3, 'b': 1, 'é': 300, 0x10FFFF: 1}
+	got, err := deserializeFrequencies(serializeFrequencies(freqMap))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, len(got), len(freqMap))
+	for char, freq := range freqMap {
+		assertEqual(t, got[char], freq)
+	}
+
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Missing run size", []byte{'a'}, "invalid run size in frequencies"},
+		{"Zero frequency", []byte{'a', 1, 0}, "invalid frequency"},
+		{"Too large total", []byte{'a', 1, 0x81, 0x80, 0x80, 0x08}, "frequencies add up to more than the coder supports"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := deserializeFrequencies(tt.input)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("deserializeFrequencies() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
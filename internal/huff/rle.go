@@ -0,0 +1,122 @@
+package huff
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+
+	"compression_tool.nobletk/internal/bitio"
+	"compression_tool.nobletk/internal/runs"
+)
+
+// The run symbols follow the last rune, so they fit next to the symbols of
+// either Alphabet. A run is coded as its symbol followed by the number of
+// repeats written with runs.Append, with rleRunA as digit 1 and rleRunB
+// as digit 2.
+const (
+	rleRunA rune = utf8.MaxRune + 1 + iota
+	rleRunB
+)
+
+// rleMinRun is the shortest run coded with run symbols, shorter runs cost
+// fewer symbols as they are.
+const rleMinRun = 4
+
+// runSymbols splits data into symbols and replaces the repeats of every
+// run of at least rleMinRun symbols with run symbols.
+func runSymbols(data []byte, alphabet Alphabet) ([]rune, error) {
+	symbols := make([]rune, 0, len(data))
+	for i := 0; i < len(data); {
+		char, sz, err := alphabet.next(data[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += sz
+
+		repeats := 0
+		for i+sz <= len(data) && bytes.Equal(data[i:i+sz], data[i-sz:i]) {
+			repeats++
+			i += sz
+		}
+
+		symbols = append(symbols, char)
+		if repeats+1 < rleMinRun {
+			for ; repeats > 0; repeats-- {
+				symbols = append(symbols, char)
+			}
+			continue
+		}
+		symbols = runs.Append(symbols, repeats, rleRunA)
+	}
+
+	return symbols, nil
+}
+
+func symbolsFrequency(symbols []rune) FrequencyMap {
+	freqMap := make(FrequencyMap)
+	for _, char := range symbols {
+		freqMap[char]++
+	}
+
+	return freqMap
+}
+
+func encSymbols(symbols []rune, preTab prefixTable) (bytes.Buffer, int, error) {
+	var bitBuff bytes.Buffer
+	bw := bitio.NewBitWriter(&bitBuff)
+
+	for _, char := range symbols {
+		c := preTab[char]
+		if c.length == 0 {
+			return bytes.Buffer{}, 0, errors.New("char not found in prefix table")
+		}
+		bw.WriteBits(c.bits, c.length)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return bytes.Buffer{}, 0, err
+	}
+
+	return bitBuff, int(bw.BitsWritten()), nil
+}
+
+// decodeRuns decodes a payload coded with run symbols.
+func decodeRuns(enc []byte, table *decodeTable, totalBits int, alphabet Alphabet) ([]byte, error) {
+	decompressed := make([]byte, 0, len(enc)*2)
+	r := bitio.NewBitReader(enc)
+
+	var last []byte
+	var d runs.Decoder
+	flush := func() {
+		for repeats := d.Take(); repeats > 0; repeats-- {
+			decompressed = append(decompressed, last...)
+		}
+	}
+
+	for r.BitsRead() < int64(totalBits) {
+		char, err := table.readSymbol(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if char == rleRunA || char == rleRunB {
+			if last == nil {
+				return nil, errors.New("run symbol without a symbol to repeat")
+			}
+			if err := d.Add(int(char - rleRunA)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		flush()
+		last = alphabet.appendSymbol(last[:0], char)
+		decompressed = append(decompressed, last...)
+	}
+	if r.BitsRead() > int64(totalBits) {
+		return nil, errors.New("last code exceeds payload bit count")
+	}
+	flush()
+
+	return decompressed, nil
+}
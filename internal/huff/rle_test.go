@@ -0,0 +1,152 @@
+package huff
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunSymbols(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []rune
+	}{
+		{"Short run", "aaab", []rune{'a', 'a', 'a', 'b'}},
+		{"Shortest coded run", "aaaab", []rune{'a', rleRunA, rleRunA, 'b'}},
+		{"Digit 2", "aaaaab", []rune{'a', rleRunB, rleRunA, 'b'}},
+		{"Run at end", "b       ", []rune{'b', ' ', rleRunB, rleRunB}},
+		{"Rune run", "éééé", []rune{'é', rleRunA, rleRunA}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbols, err := runSymbols([]byte(tt.input), AlphabetRunes)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !slices.Equal(symbols, tt.expected) {
+				t.Errorf("runSymbols() got=%x, want=%x", symbols, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunLengthRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		opts  []Option
+	}{
+		{name: "Runs across blocks", input: []byte(strings.Repeat("ab"+strings.Repeat(" ", 300), 50)), opts: []Option{WithBlockSize(1000)}},
+		{name: "Byte runs", input: bytes.Repeat([]byte{0, 0, 0, 0, 0, 0xff, 1, 2}, 1000), opts: []Option{WithAlphabet(AlphabetBytes)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRoundTrip(t, tt.input, append([]Option{WithRunLength()}, tt.opts...))
+		})
+	}
+}
+
+func TestRunLengthShrinksPaddedInput(t *testing.T) {
+	var report strings.Builder
+	for i := 0; i < 2000; i++ {
+		report.WriteString("item" + strings.Repeat(" ", 40) + "42.00" + strings.Repeat(".", 20) + "\n")
+	}
+	input := []byte(report.String())
+
+	plain, err := Compress(input)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rle, err := Compress(input, WithRunLength())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(rle) >= len(plain)*2/3 {
+		t.Errorf("run length output got %d bytes, want less than two thirds of %d", len(rle), len(plain))
+	}
+}
+
+func TestRunLengthHeader(t *testing.T) {
+	plain, err := Compress([]byte("aaaa bbb cc d"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rle, err := Compress([]byte("aaaa bbb cc d"), WithRunLength())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, plain[headerSize-1], byte(0))
+	assertEqual(t, rle[headerSize-1], featureRLE)
+
+	h, err := readStreamHeader(bytes.NewReader(rle))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, h.rle(), true)
+	assertEqual(t, h.size(), headerSize)
+}
+
+func TestRunLengthIndex(t *testing.T) {
+	input := []byte(strings.Repeat("abc"+strings.Repeat("-", 100), 200))
+	compressed := compressIndexed(t, input, WithRunLength(), WithBlockSize(1000))
+
+	s, err := OpenSeekable(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	got := make([]byte, 300)
+	if _, err := s.ReadAt(got, 5000); err != nil && err != io.EOF {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, got, input[5000:5300])
+}
+
+func TestRunLengthErrors(t *testing.T) {
+	if _, err := Compress([]byte("aaaa"), WithRunLength(), WithMethod(MethodLZ77)); err == nil || err.Error() != "run-length coding is not supported by method 2" {
+		t.Errorf("Compress() error got=%v, want run-length coding is not supported by method 2", err)
+	}
+
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Missing features", []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 0}, "data is too short"},
+		{"Unknown features", []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 0, 0x04, 0}, "unknown header features: 0x04"},
+		{"Method without runs", []byte{'H', 'U', 'F', 0x1A, 3, 0, 0, 2, 0x01, 0}, "run-length coding is not supported by method 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decompress(tt.input)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Decompress() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestDecodeRunsWithoutSymbol(t *testing.T) {
+	lengths := map[rune]int{'a': 1, rleRunA: 1}
+	table, err := newDecodeTable(canonicalCodes(lengths))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	preTab := canonicalCodes(lengths)
+
+	enc, totalBits, err := encSymbols([]rune{rleRunA, 'a'}, preTab)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = decodeRuns(enc.Bytes(), table, totalBits, AlphabetRunes)
+	if err == nil || err.Error() != "run symbol without a symbol to repeat" {
+		t.Errorf("decodeRuns() error got=%v, want run symbol without a symbol to repeat", err)
+	}
+}
@@ -0,0 +1,386 @@
+package huff
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Writer compresses everything written to it into w. Input is buffered and
+// written one batch of blocks at a time, so Close must be called to flush
+// the last blocks and the trailer.
+type Writer struct {
+	w      io.Writer
+	opts   options
+	enc    blockEncoder
+	digest *digest
+	buf    []byte
+	out    bytes.Buffer
+	// written counts the bytes of the stream written to w.
+	written int64
+	index   []indexEntry
+	// tableEntry is the index entry of the last block storing a table, -1
+	// for the table of the dictionary.
+	tableEntry  int
+	wroteHeader bool
+	closed      bool
+	err         error
+}
+
+// NewWriter returns a Writer compressing to w with the given options, an
+// invalid option is reported by the first Write or Close.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	z := &Writer{opts: newOptions(opts)}
+	z.Reset(w)
+
+	return z
+}
+
+// Reset discards the Writer state and makes it write to w, keeping the
+// options it was created with.
+func (z *Writer) Reset(w io.Writer) {
+	z.w = w
+	z.enc = blockEncoder{opts: z.opts}
+	z.buf = z.buf[:0]
+	z.out.Reset()
+	z.written = 0
+	z.index = nil
+	z.tableEntry = -1
+	z.wroteHeader = false
+	z.closed = false
+
+	z.err = z.opts.validate()
+	if z.err == nil {
+		z.digest, z.err = newDigest(z.opts.checksum)
+	}
+	if z.err == nil && z.opts.dict != nil {
+		z.enc.model = z.opts.dict.encodeModel()
+	}
+}
+
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.closed {
+		return 0, errors.New("write to closed Writer")
+	}
+
+	batchSize := z.opts.blockSize * z.opts.concurrency
+	n := 0
+	for len(p) > 0 {
+		m := min(len(p), batchSize-len(z.buf))
+		z.buf = append(z.buf, p[:m]...)
+		p = p[m:]
+		n += m
+
+		if len(z.buf) == batchSize {
+			if z.err = z.flushBlocks(false); z.err != nil {
+				return n, z.err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// flushBlocks compresses the buffered input into blocks. Unless final is
+// set, input that does not fill a whole block is kept for the next batch.
+func (z *Writer) flushBlocks(final bool) error {
+	blocks := z.splitBlocks(final)
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	z.out.Reset()
+	z.writeHeader()
+	offset := z.written + int64(z.out.Len())
+	plans, err := z.enc.encodeAll(&z.out, blocks, z.opts.concurrency)
+	if err != nil {
+		return err
+	}
+
+	size := 0
+	for _, p := range plans {
+		z.digest.Write(p.data)
+		size += len(p.data)
+		z.addIndexEntry(p, offset)
+		offset += int64(p.out.Len())
+	}
+
+	if err := z.write(); err != nil {
+		return err
+	}
+	z.buf = append(z.buf[:0], z.buf[size:]...)
+
+	return nil
+}
+
+// addIndexEntry records the block of p written at offset, when the stream
+// has an index.
+func (z *Writer) addIndexEntry(p *blockPlan, offset int64) {
+	if !z.opts.index {
+		return
+	}
+
+	e := indexEntry{offset: offset, size: int64(len(p.data)), table: len(z.index)}
+	if n := len(z.index); n > 0 {
+		last := z.index[n-1]
+		e.pos = last.pos + last.size
+	}
+	switch p.typ {
+	case blockNewTable:
+		z.tableEntry = e.table
+	case blockReuseTable:
+		e.table = z.tableEntry
+	}
+	z.index = append(z.index, e)
+}
+
+func (z *Writer) write() error {
+	n, err := z.w.Write(z.out.Bytes())
+	z.written += int64(n)
+
+	return err
+}
+
+// splitBlocks cuts the buffered input into blocks of blockSize bytes. In
+// rune mode a rune split at the end of a block is moved to the next one.
+func (z *Writer) splitBlocks(final bool) [][]byte {
+	var blocks [][]byte
+
+	data := z.buf
+	for len(data) >= z.opts.blockSize || final && len(data) > 0 {
+		n := min(len(data), z.opts.blockSize)
+		if n < len(data) || !final {
+			if z.opts.alphabet == AlphabetRunes {
+				n = runeBoundary(data[:n])
+			}
+		}
+
+		blocks = append(blocks, data[:n])
+		data = data[n:]
+	}
+
+	return blocks
+}
+
+func (z *Writer) writeHeader() {
+	if z.wroteHeader {
+		return
+	}
+	z.wroteHeader = true
+
+	h := header{
+		version:    formatVersion,
+		flags:      z.flags(),
+		maxCodeLen: byte(z.opts.maxCodeLen),
+		method:     z.opts.method,
+	}
+	if z.opts.rle {
+		h.features |= featureRLE
+	}
+	if z.opts.dict != nil {
+		h.features |= featureDictionary
+		h.dictID = z.opts.dict.id
+	}
+	writeHeader(&z.out, h)
+}
+
+func (z *Writer) flags() byte {
+	flags := byte(z.opts.checksum) | byte(z.opts.alphabet)<<flagAlphabetShift
+	if z.opts.index {
+		flags |= flagIndex
+	}
+
+	return flags
+}
+
+// runeBoundary returns the length of b without a trailing incomplete rune.
+func runeBoundary(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+
+	return len(b)
+}
+
+// Close flushes the buffered input and writes the end block and the
+// trailer, it does not close the underlying writer.
+func (z *Writer) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+
+	if len(z.buf) > 0 {
+		if z.err = z.flushBlocks(true); z.err != nil {
+			return z.err
+		}
+	}
+
+	z.out.Reset()
+	z.writeHeader()
+	writeBlock(&z.out, block{typ: blockEnd})
+	z.out.Write(z.digest.trailer())
+	if z.opts.index {
+		writeIndex(&z.out, z.index)
+	}
+	z.err = z.write()
+
+	return z.err
+}
+
+// Reader decompresses the blocks read from r one batch at a time. Streams
+// concatenated after the first one are read as well.
+type Reader struct {
+	r       *bufio.Reader
+	opts    options
+	dec     blockDecoder
+	digest  *digest
+	decoded []byte
+	// pending holds the decoded blocks of the batch not returned yet.
+	pending [][]byte
+	err     error
+}
+
+// NewReader returns a Reader decompressing r, the header of the first
+// stream is read before it returns. The legacy header-less format can only
+// be read with Decompress.
+func NewReader(r io.Reader, opts ...Option) (*Reader, error) {
+	o := newOptions(opts)
+	if o.legacy {
+		return nil, errors.New("legacy format is not supported by Reader")
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	z := &Reader{opts: o}
+	if err := z.Reset(r); err != nil {
+		return nil, err
+	}
+
+	return z, nil
+}
+
+// Reset discards the Reader state and makes it read from r.
+func (z *Reader) Reset(r io.Reader) error {
+	if br, ok := r.(*bufio.Reader); ok {
+		z.r = br
+	} else {
+		z.r = bufio.NewReader(r)
+	}
+	z.decoded = nil
+	z.pending = nil
+
+	z.err = z.readHeader()
+
+	return z.err
+}
+
+func (z *Reader) readHeader() error {
+	h, err := readStreamHeader(z.r)
+	if err != nil {
+		return err
+	}
+
+	if z.dec, err = newBlockDecoder(h, z.opts.dict); err != nil {
+		return err
+	}
+	z.digest, err = newDigest(h.checksum())
+
+	return err
+}
+
+func (z *Reader) Read(p []byte) (int, error) {
+	for len(z.decoded) == 0 {
+		if len(z.pending) > 0 {
+			z.decoded, z.pending = z.pending[0], z.pending[1:]
+			continue
+		}
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.nextBlocks()
+	}
+
+	n := copy(p, z.decoded)
+	z.decoded = z.decoded[n:]
+
+	return n, nil
+}
+
+// Close does not close the underlying reader, it only reports a decoding
+// error seen so far.
+func (z *Reader) Close() error {
+	if z.err == io.EOF {
+		return nil
+	}
+
+	return z.err
+}
+
+// nextBlocks reads and decodes up to concurrency blocks. When the end
+// block is reached the trailer is checked and the next stream, if any, is
+// started.
+func (z *Reader) nextBlocks() error {
+	var blocks []block
+	end := false
+	for len(blocks) < z.opts.concurrency {
+		b, err := readBlock(z.r)
+		if err != nil {
+			return err
+		}
+		if b.typ == blockEnd {
+			end = true
+			break
+		}
+		blocks = append(blocks, b)
+	}
+
+	decoded, err := z.dec.decodeAll(blocks, z.opts.concurrency)
+	if err != nil {
+		return err
+	}
+	for _, data := range decoded {
+		z.digest.Write(data)
+	}
+	z.pending = decoded
+
+	if !end {
+		return nil
+	}
+
+	trailerSize := z.digest.hash.Size() + 8
+	trailer, n, err := readSection(z.r, uint64(trailerSize))
+	if err != nil {
+		return truncated(err, fmt.Errorf("truncated trailer: needs %d bytes, %d left", trailerSize, n))
+	}
+	if err := z.digest.verify(trailer); err != nil {
+		return err
+	}
+	if z.dec.h.flags&flagIndex != 0 {
+		if err := skipIndex(z.r); err != nil {
+			return err
+		}
+	}
+
+	// another stream may follow this one
+	if _, err := z.r.Peek(1); err != nil {
+		return err
+	}
+
+	return z.readHeader()
+}
@@ -0,0 +1,217 @@
+package huff
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var compressed bytes.Buffer
+	zw := NewWriter(&compressed)
+	// odd sized writes split runes between writes and chunks
+	for i := 0; i < len(data); i += 7777 {
+		if _, err := zw.Write(data[i:min(i+7777, len(data))]); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	zr, err := NewReader(&compressed)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := zr.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(decompressed, data) {
+		t.Fatal("decompressed data differs from the original")
+	}
+}
+
+func TestWriterOutputDecompress(t *testing.T) {
+	input := bytes.Repeat([]byte("ééé abc "), defaultBlockSize*3/5)
+
+	var compressed bytes.Buffer
+	zw := NewWriter(&compressed, WithChecksum(ChecksumCRC64))
+	if _, err := zw.Write(input); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decompressed, err := Decompress(compressed.Bytes())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, input)
+}
+
+func TestReaderReadsCompress(t *testing.T) {
+	input := []byte("aaaa bbb cc d")
+	compressed, err := Compress(input)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	zr, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, input)
+
+	// Reset reads a second stream with the same Reader
+	if err := zr.Reset(bytes.NewReader(compressed)); err != nil {
+		t.Fatal(err.Error())
+	}
+	decompressed, err = io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, input)
+}
+
+func TestWriterEmptyAndReset(t *testing.T) {
+	var first, second bytes.Buffer
+
+	zw := NewWriter(&first, WithAlphabet(AlphabetBytes))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	zw.Reset(&second)
+	if _, err := zw.Write([]byte{0xff, 0x00, 0xff}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decompressed, err := Decompress(first.Bytes())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqual(t, len(decompressed), 0)
+
+	decompressed, err = Decompress(second.Bytes())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, []byte{0xff, 0x00, 0xff})
+}
+
+func TestWriterErrors(t *testing.T) {
+	zw := NewWriter(io.Discard, WithChecksum(Checksum(9)))
+	if _, err := zw.Write([]byte("a")); err == nil || err.Error() != "unknown checksum: 9" {
+		t.Errorf("Write() error got=%v, want=%v", err, "unknown checksum: 9")
+	}
+
+	zw = NewWriter(io.Discard)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := zw.Write([]byte("a")); err == nil || err.Error() != "write to closed Writer" {
+		t.Errorf("Write() error got=%v, want=%v", err, "write to closed Writer")
+	}
+
+	zw = NewWriter(io.Discard)
+	zw.Write([]byte{0xff})
+	if err := zw.Close(); err == nil || err.Error() != "invalid UTF-8 encoding" {
+		t.Errorf("Close() error got=%v, want=%v", err, "invalid UTF-8 encoding")
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	compressed, err := Compress([]byte("aaaa bbb cc d"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = NewReader(strings.NewReader("not compressed"))
+	if !errors.Is(err, ErrInvalidMagic) {
+		t.Errorf("NewReader() error got=%v, want=%v", err, ErrInvalidMagic)
+	}
+
+	_, err = NewReader(bytes.NewReader(compressed), WithLegacyFormat())
+	if err == nil {
+		t.Error("NewReader() with legacy format: expected error, got nil")
+	}
+
+	zr, err := NewReader(bytes.NewReader(compressed[:len(compressed)-1]))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = io.ReadAll(zr)
+	expectedErr := "truncated trailer: needs 12 bytes, 11 left"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Read() error got=%v, want=%v", err, expectedErr)
+	}
+	if zr.Close() != err {
+		t.Errorf("Close() error got=%v, want=%v", zr.Close(), err)
+	}
+
+	corrupted := append([]byte(nil), compressed...)
+	corrupted[len(corrupted)-9] ^= 0xff
+	zr, err = NewReader(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = io.ReadAll(zr)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Read() error got=%v, want=%v", err, ErrChecksumMismatch)
+	}
+}
+
+func TestDecompressConcatenatedStreams(t *testing.T) {
+	first, err := Compress([]byte("first stream, "))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	second, err := Compress([]byte{0xff, 0xfe}, WithAlphabet(AlphabetBytes), WithChecksum(ChecksumSHA256))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decompressed, err := Decompress(append(first, second...))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEqualBytes(t, decompressed, append([]byte("first stream, "), 0xff, 0xfe))
+}
+
+func TestRuneBoundary(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected int
+	}{
+		{[]byte("abc"), 3},
+		{[]byte("abé"), 4},
+		{[]byte("ab\xc3"), 2},
+		{[]byte("ab\xe2\x80"), 2},
+		{[]byte{}, 0},
+	}
+
+	for _, tt := range tests {
+		assertEqual(t, runeBoundary(tt.input), tt.expected)
+	}
+}
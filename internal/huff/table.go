@@ -0,0 +1,147 @@
+package huff
+
+import (
+	"errors"
+	"slices"
+
+	"compression_tool.nobletk/internal/bitio"
+)
+
+// tableBits is the widest index of a single lookup table, longer codes
+// continue in sub-tables.
+const tableBits = 10
+
+// decodeEntry is either a symbol with the number of bits its code takes in
+// the current table, or a link to the sub-table for longer codes.
+type decodeEntry struct {
+	char   rune
+	length uint8
+	next   *decodeTable
+}
+
+// decodeTable is indexed by the next bits of the input. Every code shorter
+// than the table width fills all slots that start with it, so one lookup
+// consumes a whole code.
+type decodeTable struct {
+	bits    uint8
+	entries []decodeEntry
+}
+
+type tableCode struct {
+	char rune
+	code
+}
+
+// parseDecodeTable builds the decode table of serialized code lengths, nil
+// when there are none.
+func parseDecodeTable(table []byte, maxCodeLen int) (*decodeTable, error) {
+	lengths, err := deserializeLengths(table)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMaxLength(lengths, maxCodeLen); err != nil {
+		return nil, err
+	}
+	if len(lengths) == 0 {
+		return nil, nil
+	}
+
+	return newDecodeTable(canonicalCodes(lengths))
+}
+
+func newDecodeTable(preTab prefixTable) (*decodeTable, error) {
+	codes := make([]tableCode, 0, len(preTab))
+	for char, c := range preTab {
+		if c.length == 0 || c.length > maxCodeLength {
+			return nil, errors.New("invalid code length")
+		}
+		codes = append(codes, tableCode{char: char, code: c})
+	}
+
+	return buildDecodeTable(codes, 0), nil
+}
+
+// buildDecodeTable builds the table for codes sharing their first depth
+// bits.
+func buildDecodeTable(codes []tableCode, depth int) *decodeTable {
+	maxLen := depth
+	for _, c := range codes {
+		maxLen = max(maxLen, int(c.length))
+	}
+	width := min(maxLen-depth, tableBits)
+
+	t := &decodeTable{
+		bits:    uint8(width),
+		entries: make([]decodeEntry, 1<<width),
+	}
+
+	long := make(map[uint64][]tableCode)
+	for _, c := range codes {
+		rest := int(c.length) - depth
+		if rest <= width {
+			idx := (c.bits & (1<<rest - 1)) << (width - rest)
+			for i := idx; i < idx+1<<(width-rest); i++ {
+				t.entries[i] = decodeEntry{char: c.char, length: uint8(rest)}
+			}
+			continue
+		}
+
+		idx := (c.bits >> (rest - width)) & (1<<width - 1)
+		long[idx] = append(long[idx], c)
+	}
+
+	indices := make([]uint64, 0, len(long))
+	for idx := range long {
+		indices = append(indices, idx)
+	}
+	slices.Sort(indices)
+	for _, idx := range indices {
+		t.entries[idx] = decodeEntry{
+			length: uint8(width),
+			next:   buildDecodeTable(long[idx], depth+width),
+		}
+	}
+
+	return t
+}
+
+func decode(enc []byte, table *decodeTable, totalBits int, alphabet Alphabet) ([]byte, error) {
+	decompressed := make([]byte, 0, len(enc)*2)
+	r := bitio.NewBitReader(enc)
+
+	bitIndex := 0
+	for bitIndex < totalBits {
+		entry := decodeEntry{next: table}
+		for entry.next != nil {
+			t := entry.next
+			entry = t.entries[r.Peek(t.bits)]
+			if entry.length == 0 {
+				return nil, errors.New("invalid code in payload")
+			}
+			r.Consume(entry.length)
+			bitIndex += int(entry.length)
+		}
+
+		if bitIndex > totalBits {
+			return nil, errors.New("last code exceeds payload bit count")
+		}
+		decompressed = alphabet.appendSymbol(decompressed, entry.char)
+	}
+
+	return decompressed, nil
+}
+
+// readSymbol decodes the next symbol from r.
+func (t *decodeTable) readSymbol(r *bitio.BitReader) (rune, error) {
+	entry := decodeEntry{next: t}
+	for entry.next != nil {
+		t := entry.next
+		entry = t.entries[r.Peek(t.bits)]
+		if entry.length == 0 {
+			return 0, errors.New("invalid code in payload")
+		}
+		r.Consume(entry.length)
+	}
+
+	return entry.char, nil
+}
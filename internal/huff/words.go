@@ -0,0 +1,316 @@
+package huff
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"compression_tool.nobletk/internal/bitio"
+)
+
+// wordBase is the symbol of the first dictionary word, the words follow
+// the last rune so they fit next to the literals of either Alphabet.
+const wordBase rune = utf8.MaxRune + 1
+
+// maxTokenLen cuts longer words and whitespace runs into several tokens,
+// which keeps the dictionary of a block small.
+const maxTokenLen = 64
+
+// wordMinCount is the fewest times a token must occur in a block to be
+// considered for the dictionary.
+const wordMinCount = 2
+
+// token is a word, a whitespace run or a single other symbol, n is its
+// length in symbols.
+type token struct {
+	text string
+	n    int
+}
+
+type tokenClass int
+
+const (
+	classOther tokenClass = iota
+	classWord
+	classSpace
+)
+
+// classOf returns the class of char. With AlphabetBytes every byte past
+// ASCII belongs to words, so multibyte UTF-8 letters stay together.
+func classOf(char rune, alphabet Alphabet) tokenClass {
+	switch {
+	case alphabet == AlphabetBytes && char >= utf8.RuneSelf:
+		return classWord
+	case char == '_' || unicode.IsLetter(char) || unicode.IsDigit(char):
+		return classWord
+	case unicode.IsSpace(char):
+		return classSpace
+	}
+
+	return classOther
+}
+
+// splitTokens splits data into runs of letters, digits and underscores,
+// runs of whitespace and single symbols of any other kind.
+func splitTokens(data []byte, alphabet Alphabet) ([]token, error) {
+	var tokens []token
+	start, n := 0, 0
+	last := classOther
+	for i := 0; i < len(data); {
+		char, sz, err := alphabet.next(data[i:])
+		if err != nil {
+			return nil, err
+		}
+
+		class := classOf(char, alphabet)
+		if n > 0 && (class != last || class == classOther || n == maxTokenLen) {
+			tokens = append(tokens, token{text: string(data[start:i]), n: n})
+			start, n = i, 0
+		}
+		last = class
+		n++
+		i += sz
+	}
+	if n > 0 {
+		tokens = append(tokens, token{text: string(data[start:]), n: n})
+	}
+
+	return tokens, nil
+}
+
+func init() {
+	RegisterCoder(MethodWords, wordsCoder{})
+}
+
+// wordsModel holds the dictionary of a block in ascending order with the
+// code lengths and codes of its symbols when encoding, or its decode table
+// when decoding.
+type wordsModel struct {
+	words   []string
+	lengths map[rune]int
+	codes   prefixTable
+	table   *decodeTable
+}
+
+// wordsCoder codes blocks with one Huffman table over literal symbols and
+// a dictionary of the words and whitespace runs that recur in the block.
+type wordsCoder struct{}
+
+// wordSymbols returns the symbols coding tokens, a dictionary word as its
+// symbol and any other token spelled out as literals.
+func wordSymbols(tokens []token, dict map[string]rune, alphabet Alphabet) []rune {
+	symbols := make([]rune, 0, len(tokens))
+	for _, tok := range tokens {
+		if char, ok := dict[tok.text]; ok {
+			symbols = append(symbols, char)
+			continue
+		}
+		if alphabet == AlphabetBytes {
+			for i := 0; i < len(tok.text); i++ {
+				symbols = append(symbols, rune(tok.text[i]))
+			}
+			continue
+		}
+		for _, char := range tok.text {
+			symbols = append(symbols, char)
+		}
+	}
+
+	return symbols
+}
+
+// BuildModel puts a token in the dictionary when its estimated saving
+// outweighs the bytes it takes in the model. A token spelled out costs
+// about the order-0 entropy of the block per symbol, a dictionary word
+// about the information of its own frequency among the tokens.
+func (wordsCoder) BuildModel(data []byte, cfg CoderConfig) (Model, error) {
+	tokens, err := splitTokens(data, cfg.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	sizes := make(map[string]int)
+	literals := make(FrequencyMap)
+	total := 0
+	for _, tok := range tokens {
+		counts[tok.text]++
+		sizes[tok.text] = tok.n
+		total += tok.n
+	}
+	for _, char := range wordSymbols(tokens, nil, cfg.Alphabet) {
+		literals[char]++
+	}
+
+	perSymbol := 0.0
+	for _, freq := range literals {
+		p := float64(freq) / float64(total)
+		perSymbol -= p * math.Log2(p)
+	}
+
+	type candidate struct {
+		text string
+		gain float64
+	}
+	var candidates []candidate
+	for text, freq := range counts {
+		if freq < wordMinCount || sizes[text] < 2 {
+			continue
+		}
+		spelled := float64(sizes[text]) * perSymbol
+		coded := math.Log2(float64(len(tokens)) / float64(freq))
+		if gain := float64(freq)*(spelled-coded) - 8*float64(len(text)+2); gain > 0 {
+			candidates = append(candidates, candidate{text, gain})
+		}
+	}
+
+	// a length limit bounds the number of symbols, the words saving the
+	// most are kept
+	if cfg.MaxCodeLen > 0 && cfg.MaxCodeLen < 31 {
+		room := max(0, 1<<cfg.MaxCodeLen-len(literals))
+		if len(candidates) > room {
+			slices.SortFunc(candidates, func(a, b candidate) int {
+				if a.gain != b.gain {
+					return cmp.Compare(b.gain, a.gain)
+				}
+				return strings.Compare(a.text, b.text)
+			})
+			candidates = candidates[:room]
+		}
+	}
+
+	words := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		words = append(words, c.text)
+	}
+	slices.Sort(words)
+
+	dict := make(map[string]rune, len(words))
+	for i, word := range words {
+		dict[word] = wordBase + rune(i)
+	}
+	lengths, err := buildLengths(symbolsFrequency(wordSymbols(tokens, dict, cfg.Alphabet)), cfg.MaxCodeLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wordsModel{words: words, lengths: lengths, codes: canonicalCodes(lengths)}, nil
+}
+
+// WriteModel stores the number of words, then every word as the length of
+// the prefix it shares with the word before it, the length of the rest and
+// the rest, followed by the code length table.
+func (wordsCoder) WriteModel(m Model) []byte {
+	wm := m.(*wordsModel)
+
+	b := binary.AppendUvarint(nil, uint64(len(wm.words)))
+	prev := ""
+	for _, word := range wm.words {
+		shared := 0
+		for shared < min(len(prev), len(word)) && prev[shared] == word[shared] {
+			shared++
+		}
+		b = binary.AppendUvarint(b, uint64(shared))
+		b = binary.AppendUvarint(b, uint64(len(word)-shared))
+		b = append(b, word[shared:]...)
+		prev = word
+	}
+
+	return append(b, serializeLengths(wm.lengths)...)
+}
+
+func (wordsCoder) Encode(m Model, data []byte, cfg CoderConfig) ([]byte, int, error) {
+	wm := m.(*wordsModel)
+	tokens, err := splitTokens(data, cfg.Alphabet)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dict := make(map[string]rune, len(wm.words))
+	for i, word := range wm.words {
+		dict[word] = wordBase + rune(i)
+	}
+	bitBuff, totalBits, err := encSymbols(wordSymbols(tokens, dict, cfg.Alphabet), wm.codes)
+
+	return bitBuff.Bytes(), totalBits, err
+}
+
+func (wordsCoder) ReadModel(b []byte, cfg CoderConfig) (Model, error) {
+	r := bytes.NewReader(b)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.New("invalid word count")
+	}
+	if count > uint64(len(b)) {
+		return nil, fmt.Errorf("word count is too large: %d", count)
+	}
+
+	words := make([]string, 0, count)
+	prev := ""
+	for i := uint64(0); i < count; i++ {
+		shared, err := binary.ReadUvarint(r)
+		if err != nil || shared > uint64(len(prev)) {
+			return nil, fmt.Errorf("invalid shared prefix of word %d", i)
+		}
+		rest, err := binary.ReadUvarint(r)
+		if err != nil || rest > uint64(r.Len()) || shared+rest == 0 {
+			return nil, fmt.Errorf("word %d is truncated", i)
+		}
+
+		suffix := make([]byte, rest)
+		r.Read(suffix)
+		prev = prev[:shared] + string(suffix)
+		words = append(words, prev)
+	}
+
+	lengths, err := deserializeLengths(b[len(b)-r.Len():])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMaxLength(lengths, cfg.MaxCodeLen); err != nil {
+		return nil, err
+	}
+	table, err := newDecodeTable(canonicalCodes(lengths))
+	if err != nil {
+		return nil, err
+	}
+
+	return &wordsModel{words: words, lengths: lengths, table: table}, nil
+}
+
+func (wordsCoder) Decode(m Model, payload []byte, totalBits int, cfg CoderConfig) ([]byte, error) {
+	wm := m.(*wordsModel)
+	if len(wm.lengths) == 0 && totalBits != 0 {
+		return nil, errors.New("payload without code lengths")
+	}
+
+	decompressed := make([]byte, 0, len(payload)*3)
+	r := bitio.NewBitReader(payload)
+	for r.BitsRead() < int64(totalBits) {
+		char, err := wm.table.readSymbol(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if char < wordBase {
+			decompressed = cfg.Alphabet.appendSymbol(decompressed, char)
+			continue
+		}
+		if int(char-wordBase) >= len(wm.words) {
+			return nil, fmt.Errorf("word out of range: %d", char-wordBase)
+		}
+		decompressed = append(decompressed, wm.words[char-wordBase]...)
+	}
+	if r.BitsRead() > int64(totalBits) {
+		return nil, errors.New("last code exceeds payload bit count")
+	}
+
+	return decompressed, nil
+}
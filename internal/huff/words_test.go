@@ -0,0 +1,109 @@
+package huff
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplitTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		alphabet Alphabet
+		expected []string
+	}{
+		{"Empty", "", AlphabetRunes, nil},
+		{"Words and spaces", "hello,  world_1!\n", AlphabetRunes, []string{"hello", ",", "  ", "world_1", "!", "\n"}},
+		{"Punctuation", "a::b", AlphabetRunes, []string{"a", ":", ":", "b"}},
+		{"Runes", "naïve café", AlphabetRunes, []string{"naïve", " ", "café"}},
+		{"Bytes", "naïve café", AlphabetBytes, []string{"naïve", " ", "café"}},
+		{"Long word", strings.Repeat("a", 70), AlphabetRunes, []string{strings.Repeat("a", 64), "aaaaaa"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := splitTokens([]byte(tt.input), tt.alphabet)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			var texts []string
+			for _, tok := range tokens {
+				texts = append(texts, tok.text)
+			}
+			assertEqual(t, strings.Join(texts, "|"), strings.Join(tt.expected, "|"))
+			assertEqual(t, len(texts), len(tt.expected))
+		})
+	}
+}
+
+func TestWordsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"Single word", []byte("word")},
+		{"Repeated words", []byte(strings.Repeat("GET /index.html 200\n", 1000))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRoundTrip(t, tt.input, []Option{WithMethod(MethodWords)})
+		})
+	}
+}
+
+func TestWordsBeatRunes(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	data = data[:500000]
+
+	huffman, err := Compress(data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	words, err := Compress(data, WithMethod(MethodWords))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(words) >= len(huffman)*9/10 {
+		t.Errorf("words size got %d, want at least 10%% below huffman size %d", len(words), len(huffman))
+	}
+}
+
+func TestReadWordsModelErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		model       []byte
+		expectedErr string
+	}{
+		{"Empty", nil, "invalid word count"},
+		{"Too many words", []byte{9, 1, 1}, "word count is too large: 9"},
+		{"Shared prefix too long", []byte{2, 0, 2, 'a', 'b', 3, 1, 'c'}, "invalid shared prefix of word 1"},
+		{"Truncated word", []byte{1, 0, 5, 'a', 'b'}, "word 0 is truncated"},
+		{"Empty word", []byte{1, 0, 0}, "word 0 is truncated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := wordsCoder{}.ReadModel(tt.model, CoderConfig{})
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("ReadModel() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+
+	model, payload, totalBits := codeBlock(t, wordsCoder{}, []byte(strings.Repeat("word ", 50)), CoderConfig{})
+	m, err := wordsCoder{}.ReadModel(model, CoderConfig{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	m.(*wordsModel).words = nil
+	if _, err := (wordsCoder{}).Decode(m, payload, totalBits, CoderConfig{}); err == nil || !strings.HasPrefix(err.Error(), "word out of range") {
+		t.Errorf("Decode() error got=%v, want word out of range", err)
+	}
+}
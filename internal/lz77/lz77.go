@@ -0,0 +1,201 @@
+// Package lz77 finds repeated strings in a buffer with hash chains, the
+// way DEFLATE encoders do, and expands the matches back.
+package lz77
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// MinMatch is the shortest match worth a back reference.
+	MinMatch = 3
+	// MaxMatch is the longest match, it fits the DEFLATE length codes.
+	MaxMatch = 258
+
+	// MinWindow and MaxWindow bound the window size, which must be a power
+	// of two.
+	MinWindow = 1 << 8
+	MaxWindow = 1 << 24
+
+	hashBits = 15
+	// maxChain is the number of candidates tried at every position.
+	maxChain = 128
+)
+
+// Token is a literal byte when Length is zero, otherwise it repeats Length
+// bytes found Distance bytes back.
+type Token struct {
+	Literal  byte
+	Length   int
+	Distance int
+}
+
+// ValidWindow reports an error unless window is a power of two between
+// MinWindow and MaxWindow.
+func ValidWindow(window int) error {
+	if window < MinWindow || window > MaxWindow || window&(window-1) != 0 {
+		return fmt.Errorf("invalid window size: %d", window)
+	}
+
+	return nil
+}
+
+// matcher keeps, for every 3 byte hash, the chain of earlier positions
+// starting with the same hash. Chains are stored in a ring of window
+// entries, so positions that left the window are overwritten.
+type matcher struct {
+	data   []byte
+	window int
+	head   []int32
+	prev   []int32
+}
+
+func newMatcher(data []byte, window int) *matcher {
+	m := &matcher{
+		data:   data,
+		window: window,
+		head:   make([]int32, 1<<hashBits),
+		prev:   make([]int32, min(window, len(data))),
+	}
+	for i := range m.head {
+		m.head[i] = -1
+	}
+
+	return m
+}
+
+func (m *matcher) hash(pos int) uint32 {
+	b := m.data[pos:]
+	v := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+
+	return (v * 2654435761) >> (32 - hashBits)
+}
+
+// insert adds pos to the chain of its hash.
+func (m *matcher) insert(pos int) {
+	if pos+MinMatch > len(m.data) {
+		return
+	}
+
+	h := m.hash(pos)
+	m.prev[pos%len(m.prev)] = m.head[h]
+	m.head[h] = int32(pos)
+}
+
+// longest returns the longest earlier match for the bytes at pos within the
+// window, a length below MinMatch means none was found.
+func (m *matcher) longest(pos int) (int, int) {
+	if pos+MinMatch > len(m.data) {
+		return 0, 0
+	}
+
+	maxLen := min(MaxMatch, len(m.data)-pos)
+	limit := pos - m.window
+	bestLen, bestDist := 0, 0
+
+	cand := int(m.head[m.hash(pos)])
+	for chain := maxChain; cand >= 0 && cand > limit && chain > 0; chain-- {
+		if m.data[cand+bestLen] == m.data[pos+bestLen] {
+			n := 0
+			for n < maxLen && m.data[cand+n] == m.data[pos+n] {
+				n++
+			}
+			if n > bestLen {
+				bestLen, bestDist = n, pos-cand
+				if n == maxLen {
+					break
+				}
+			}
+		}
+		cand = int(m.prev[cand%len(m.prev)])
+	}
+
+	return bestLen, bestDist
+}
+
+// match returns the longest match at pos and inserts pos.
+func (m *matcher) match(pos int) (int, int) {
+	length, dist := m.longest(pos)
+	m.insert(pos)
+
+	return length, dist
+}
+
+// Parse splits data into literals and matches at most window bytes back.
+// A match is delayed by one byte when the next position has a longer one.
+func Parse(data []byte, window int) []Token {
+	if len(data) == 0 {
+		return nil
+	}
+
+	m := newMatcher(data, window)
+	tokens := make([]Token, 0, len(data)/4)
+
+	length, dist := m.match(0)
+	for i := 0; i < len(data); {
+		if length < MinMatch {
+			tokens = append(tokens, Token{Literal: data[i]})
+			i++
+			if i < len(data) {
+				length, dist = m.match(i)
+			}
+			continue
+		}
+
+		if i+1 < len(data) {
+			nextLen, nextDist := m.match(i + 1)
+			if nextLen > length {
+				tokens = append(tokens, Token{Literal: data[i]})
+				i++
+				length, dist = nextLen, nextDist
+				continue
+			}
+		}
+
+		tokens = append(tokens, Token{Length: length, Distance: dist})
+		for j := i + 2; j < i+length; j++ {
+			m.insert(j)
+		}
+		i += length
+		if i < len(data) {
+			length, dist = m.match(i)
+		}
+	}
+
+	return tokens
+}
+
+// AppendMatch appends length bytes found distance bytes back in dst. Bytes
+// are copied one by one, so a match may overlap its own output.
+func AppendMatch(dst []byte, length, distance int) ([]byte, error) {
+	if distance <= 0 || distance > len(dst) {
+		return dst, errors.New("match distance out of range")
+	}
+
+	start := len(dst) - distance
+	for i := 0; i < length; i++ {
+		dst = append(dst, dst[start+i])
+	}
+
+	return dst, nil
+}
+
+// Expand rebuilds the data split by Parse.
+func Expand(tokens []Token) ([]byte, error) {
+	var data []byte
+	for _, t := range tokens {
+		if t.Length == 0 {
+			data = append(data, t.Literal)
+			continue
+		}
+
+		var err error
+		data, err = AppendMatch(data, t.Length, t.Distance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
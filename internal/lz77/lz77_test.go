@@ -0,0 +1,94 @@
+package lz77
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseExpand(t *testing.T) {
+	data, err := os.ReadFile("../../cmd/app/test/testdata/test.txt")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name   string
+		input  []byte
+		window int
+	}{
+		{"Empty", nil, 1 << 15},
+		{"Short", []byte("ab"), 1 << 15},
+		{"Overlapping run", []byte(strings.Repeat("a", 1000)), 1 << 15},
+		{"Repeated words", []byte(strings.Repeat("the cat sat on the mat. ", 200)), 1 << 8},
+		{"Test file", data[:1<<20], 1 << 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := Parse(tt.input, tt.window)
+
+			for _, tok := range tokens {
+				if tok.Length != 0 && (tok.Length < MinMatch || tok.Length > MaxMatch || tok.Distance > tt.window) {
+					t.Fatalf("invalid match length %d distance %d", tok.Length, tok.Distance)
+				}
+			}
+
+			expanded, err := Expand(tokens)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !bytes.Equal(expanded, tt.input) {
+				t.Fatal("expanded data differs from the input")
+			}
+		})
+	}
+}
+
+func TestParseFindsMatches(t *testing.T) {
+	tokens := Parse([]byte("abcdefabcdefabcdef"), 1<<15)
+
+	expected := []Token{
+		{Literal: 'a'}, {Literal: 'b'}, {Literal: 'c'},
+		{Literal: 'd'}, {Literal: 'e'}, {Literal: 'f'},
+		{Length: 12, Distance: 6},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("got %v, want %v", tokens, expected)
+	}
+	for i := range tokens {
+		if tokens[i] != expected[i] {
+			t.Fatalf("got %v, want %v", tokens, expected)
+		}
+	}
+}
+
+func TestAppendMatchOutOfRange(t *testing.T) {
+	for _, dist := range []int{0, 4} {
+		_, err := AppendMatch([]byte("abc"), 3, dist)
+		if err == nil || err.Error() != "match distance out of range" {
+			t.Errorf("AppendMatch() distance %d error got=%v", dist, err)
+		}
+	}
+}
+
+func TestValidWindow(t *testing.T) {
+	tests := []struct {
+		window int
+		valid  bool
+	}{
+		{1 << 8, true},
+		{1 << 15, true},
+		{1 << 24, true},
+		{1 << 7, false},
+		{1 << 25, false},
+		{1000, false},
+	}
+
+	for _, tt := range tests {
+		if err := ValidWindow(tt.window); (err == nil) != tt.valid {
+			t.Errorf("ValidWindow(%d) got=%v", tt.window, err)
+		}
+	}
+}
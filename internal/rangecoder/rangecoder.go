@@ -0,0 +1,125 @@
+// Package rangecoder implements a byte oriented range coder with carry
+// propagation, as used by LZMA, for symbols given by their cumulative
+// frequency in a static model.
+package rangecoder
+
+import "errors"
+
+const (
+	// MaxTotal bounds the sum of the frequencies of a model, so every
+	// symbol keeps at least one value of the range.
+	MaxTotal = 1 << 24
+
+	top = 1 << 24
+)
+
+// Encoder appends the coded symbols to a byte slice.
+type Encoder struct {
+	low       uint64
+	rng       uint32
+	cache     byte
+	cacheSize int
+	out       []byte
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{rng: 0xFFFFFFFF, cacheSize: 1}
+}
+
+// Encode codes the symbol taking freq values from start, out of total.
+func (e *Encoder) Encode(start, freq, total uint32) {
+	r := e.rng / total
+	e.low += uint64(r * start)
+	e.rng = r * freq
+	for e.rng < top {
+		e.rng <<= 8
+		e.shiftLow()
+	}
+}
+
+// shiftLow moves the top byte of low out, holding back bytes a later carry
+// may still change.
+func (e *Encoder) shiftLow() {
+	if uint32(e.low) < 0xFF000000 || e.low >= 1<<32 {
+		carry := byte(e.low >> 32)
+		temp := e.cache
+		for ; e.cacheSize > 0; e.cacheSize-- {
+			e.out = append(e.out, temp+carry)
+			temp = 0xFF
+		}
+		e.cache = byte(e.low >> 24)
+	}
+	e.cacheSize++
+	e.low = (e.low & 0x00FFFFFF) << 8
+}
+
+// Finish flushes the state of the coder and returns the coded bytes.
+func (e *Encoder) Finish() []byte {
+	for i := 0; i < 5; i++ {
+		e.shiftLow()
+	}
+
+	return e.out
+}
+
+// Decoder reads the symbols coded by an Encoder.
+type Decoder struct {
+	code uint32
+	rng  uint32
+	// r is the size of one frequency value in the current range.
+	r   uint32
+	in  []byte
+	pos int
+}
+
+func NewDecoder(in []byte) (*Decoder, error) {
+	if len(in) < 5 {
+		return nil, errors.New("range coder data is too short")
+	}
+	if in[0] != 0 {
+		return nil, errors.New("invalid range coder data")
+	}
+
+	d := &Decoder{rng: 0xFFFFFFFF, in: in, pos: 5}
+	for _, b := range in[1:5] {
+		d.code = d.code<<8 | uint32(b)
+	}
+
+	return d, nil
+}
+
+// Freq returns the value the next symbol covers out of total, which is
+// then found in the model and passed to Decode.
+func (d *Decoder) Freq(total uint32) uint32 {
+	d.r = d.rng / total
+
+	return min(d.code/d.r, total-1)
+}
+
+// Decode removes the symbol taking freq values from start, it must follow
+// Freq.
+func (d *Decoder) Decode(start, freq uint32) error {
+	d.code -= start * d.r
+	d.rng = d.r * freq
+	if d.code >= d.rng {
+		return errors.New("invalid range coder data")
+	}
+
+	for d.rng < top {
+		var b byte
+		if d.pos < len(d.in) {
+			b = d.in[d.pos]
+		}
+		d.pos++
+		d.code = d.code<<8 | uint32(b)
+		d.rng <<= 8
+	}
+
+	return nil
+}
+
+// Overrun reports whether the decoder needed more bytes than the coded
+// data holds, which only happens with corrupted data.
+func (d *Decoder) Overrun() bool {
+	return d.pos > len(d.in)
+}
@@ -0,0 +1,129 @@
+package rangecoder
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// model is a static model for the tests: the cumulative frequency of
+// every symbol plus the total as the last entry.
+type model []uint32
+
+func newModel(freqs []uint32) model {
+	m := make(model, len(freqs)+1)
+	for i, f := range freqs {
+		m[i+1] = m[i] + f
+	}
+
+	return m
+}
+
+func (m model) total() uint32 {
+	return m[len(m)-1]
+}
+
+func (m model) find(v uint32) int {
+	return sort.Search(len(m)-1, func(i int) bool { return m[i+1] > v })
+}
+
+func roundTrip(t *testing.T, freqs []uint32, symbols []int) []byte {
+	t.Helper()
+	m := newModel(freqs)
+
+	e := NewEncoder()
+	for _, s := range symbols {
+		e.Encode(m[s], freqs[s], m.total())
+	}
+	out := e.Finish()
+
+	d, err := NewDecoder(out)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i, want := range symbols {
+		s := m.find(d.Freq(m.total()))
+		if s != want {
+			t.Fatalf("symbol %d got= %d, want= %d", i, s, want)
+		}
+		if err := d.Decode(m[s], freqs[s]); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if d.Overrun() {
+		t.Fatal("decoder read past the coded data")
+	}
+
+	return out
+}
+
+func TestRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name  string
+		freqs []uint32
+		count int
+	}{
+		{"Empty", []uint32{1, 1}, 0},
+		{"Single symbol", []uint32{5}, 1000},
+		{"Uniform", []uint32{1, 1, 1, 1, 1, 1, 1, 1}, 10000},
+		{"Skewed", []uint32{60000, 1, 1, 3000}, 10000},
+		{"Large total", []uint32{MaxTotal - 2, 1, 1}, 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newModel(tt.freqs)
+			symbols := make([]int, tt.count)
+			for i := range symbols {
+				symbols[i] = m.find(uint32(rng.Int63n(int64(m.total()))))
+			}
+			roundTrip(t, tt.freqs, symbols)
+		})
+	}
+}
+
+func TestCodedSizeIsNearEntropy(t *testing.T) {
+	freqs := []uint32{120, 42, 42, 37, 32, 24, 7, 2}
+	m := newModel(freqs)
+
+	var symbols []int
+	entropy := 0.0
+	for s, f := range freqs {
+		for i := 0; i < int(f)*100; i++ {
+			symbols = append(symbols, s)
+		}
+		p := float64(f) / float64(m.total())
+		entropy -= float64(f) * 100 * math.Log2(p)
+	}
+	rand.New(rand.NewSource(2)).Shuffle(len(symbols), func(i, j int) {
+		symbols[i], symbols[j] = symbols[j], symbols[i]
+	})
+
+	out := roundTrip(t, freqs, symbols)
+	if bits := float64(len(out) * 8); bits > entropy*1.001+64 {
+		t.Errorf("coded size got %.0f bits, want close to %.0f", bits, entropy)
+	}
+}
+
+func TestNewDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{"Short", []byte{0, 1, 2}, "range coder data is too short"},
+		{"Bad first byte", []byte{1, 0, 0, 0, 0}, "invalid range coder data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDecoder(tt.input)
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("NewDecoder() error got=%v, want=%v", err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package readwrite
 import (
 	"bufio"
 	"errors"
+	"io"
 	"os"
+	"unicode/utf8"
 )
 
 func ReadFile(filePath string) ([]byte, error) {
@@ -35,3 +37,106 @@ func WriteFile(fileName string, data []byte) error {
 
 	return nil
 }
+
+// StreamFile passes the input file through transform into the output file
+// without loading either of them in memory.
+func StreamFile(inputPath, outputPath string, transform func(dst io.Writer, src io.Reader) error) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	defer input.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	defer output.Close()
+
+	writer := bufio.NewWriter(output)
+	err = transform(writer, bufio.NewReader(input))
+	if err != nil {
+		return err
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return output.Close()
+}
+
+// ExtractFile gives extract random access to the input file, so it can
+// write part of it into the output file without reading the rest.
+func ExtractFile(inputPath, outputPath string, extract func(dst io.Writer, src io.ReaderAt, size int64) error) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	defer input.Close()
+
+	info, err := input.Stat()
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	defer output.Close()
+
+	writer := bufio.NewWriter(output)
+	err = extract(writer, input, info.Size())
+	if err != nil {
+		return err
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
+	return output.Close()
+}
+
+// IsUTF8File reports whether the file holds valid UTF-8, reading it in
+// chunks.
+func IsUTF8File(filePath string) (bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, errors.New(err.Error())
+	}
+	defer file.Close()
+
+	buff := make([]byte, 64*1024)
+	carry := 0
+	for {
+		n, err := file.Read(buff[carry:])
+		n += carry
+
+		// keep a rune split at the end of the chunk for the next read
+		valid := n
+		for i := n - 1; i >= 0 && i >= n-utf8.UTFMax; i-- {
+			if utf8.RuneStart(buff[i]) {
+				if !utf8.FullRune(buff[i:n]) {
+					valid = i
+				}
+				break
+			}
+		}
+
+		if err == io.EOF {
+			return utf8.Valid(buff[:n]), nil
+		}
+		if err != nil {
+			return false, errors.New(err.Error())
+		}
+
+		if !utf8.Valid(buff[:valid]) {
+			return false, nil
+		}
+		carry = copy(buff, buff[valid:n])
+	}
+}
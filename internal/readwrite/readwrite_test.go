@@ -0,0 +1,68 @@
+package readwrite
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsUTF8File(t *testing.T) {
+	// the 3 byte rune straddles the 64 KiB read boundary
+	split := append(bytes.Repeat([]byte{'a'}, 64*1024-1), "…"...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected bool
+	}{
+		{"Empty", []byte{}, true},
+		{"ASCII", []byte("hello"), true},
+		{"Rune across chunks", split, true},
+		{"Invalid byte", []byte{'a', 0xff, 'b'}, false},
+		{"Truncated rune at end", []byte{'a', 0xe2, 0x80}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			actual, err := IsUTF8File(path)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if actual != tt.expected {
+				t.Errorf("IsUTF8File() got= %v, want= %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStreamFile(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input")
+	output := filepath.Join(dir, "output")
+	if err := os.WriteFile(input, []byte("stream me"), 0o644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err := StreamFile(input, output, func(dst io.Writer, src io.Reader) error {
+		_, err := io.Copy(dst, src)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	actual, err := ReadFile(output)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(actual) != "stream me" {
+		t.Errorf("StreamFile() got= %q, want= %q", actual, "stream me")
+	}
+}
@@ -0,0 +1,50 @@
+// Package runs codes run lengths in bijective base 2 with two digit
+// symbols, the way bzip2 codes its runs of zeros.
+package runs
+
+import "errors"
+
+// MaxLength bounds a run read by a Decoder so a corrupted stream fails
+// instead of allocating without limit.
+const MaxLength = 1 << 30
+
+// Append appends the length of a run, at least 1, in bijective base 2,
+// least significant digit first, with runA as digit 1 and runA+1 as digit
+// 2. Symbols of any other value can follow the run.
+func Append[S ~uint16 | ~int32](out []S, run int, runA S) []S {
+	for ; run > 0; run = (run - 1) / 2 {
+		out = append(out, runA+S((run-1)&1))
+	}
+
+	return out
+}
+
+// Decoder reads back the runs written by Append one digit at a time, its
+// zero value is ready to use.
+type Decoder struct {
+	run    int
+	weight int
+}
+
+// Add adds the next digit of the run, 0 for digit 1 and 1 for digit 2.
+func (d *Decoder) Add(digit int) error {
+	if d.weight == 0 {
+		d.weight = 1
+	}
+	d.run += d.weight << digit
+	d.weight <<= 1
+	if d.run > MaxLength {
+		return errors.New("run is too long")
+	}
+
+	return nil
+}
+
+// Take returns the run read so far, zero without one, and starts the
+// next run.
+func (d *Decoder) Take() int {
+	run := d.run
+	*d = Decoder{}
+
+	return run
+}
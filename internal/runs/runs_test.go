@@ -0,0 +1,56 @@
+package runs
+
+import "testing"
+
+func TestAppend(t *testing.T) {
+	tests := []struct {
+		run      int
+		expected []uint16
+	}{
+		{0, nil},
+		{1, []uint16{0}},
+		{2, []uint16{1}},
+		{3, []uint16{0, 0}},
+		{4, []uint16{1, 0}},
+		{6, []uint16{1, 1}},
+	}
+
+	for _, tt := range tests {
+		actual := Append(nil, tt.run, uint16(0))
+		if len(actual) != len(tt.expected) {
+			t.Fatalf("Append(%d) got= %v, want= %v", tt.run, actual, tt.expected)
+		}
+		for i := range actual {
+			if actual[i] != tt.expected[i] {
+				t.Fatalf("Append(%d) got= %v, want= %v", tt.run, actual, tt.expected)
+			}
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	const runA rune = 0x110000
+	for run := 1; run <= 1000; run++ {
+		var d Decoder
+		for _, s := range Append(nil, run, runA) {
+			if err := d.Add(int(s - runA)); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+		if actual := d.Take(); actual != run {
+			t.Fatalf("got= %d, want= %d", actual, run)
+		}
+		if d.Take() != 0 {
+			t.Fatal("Take() did not reset the run")
+		}
+	}
+
+	var d Decoder
+	var err error
+	for i := 0; i < 31 && err == nil; i++ {
+		err = d.Add(1)
+	}
+	if err == nil {
+		t.Error("Add() accepted a run longer than MaxLength")
+	}
+}